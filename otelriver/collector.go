@@ -0,0 +1,227 @@
+package otelriver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/riverqueue/river/riverdriver"
+	"github.com/riverqueue/river/rivershared/util/dbutil"
+	"github.com/riverqueue/river/rivertype"
+)
+
+// CollectorConfig is configuration for River's OpenTelemetry job collector.
+type CollectorConfig struct {
+	// DurationUnit selects the unit in which duration metrics like
+	// `river.job_oldest_available_age` are emitted.
+	//
+	// Must be one of "ms" (milliseconds) or "s" (seconds). Defaults to seconds.
+	DurationUnit string
+
+	// MeterProvider is a MeterProvider to base metrics on. May be left as nil
+	// to use the default global provider.
+	MeterProvider metric.MeterProvider
+
+	// Schema is the database schema containing River's tables. May be left
+	// empty to use the default search path.
+	Schema string
+
+	// States are the job states for which job counts are reported. Defaults to
+	// all states except `completed` and `cancelled`, which tend to be
+	// voluminous and aren't useful for alerting on a backlog.
+	States []rivertype.JobState
+}
+
+// Collector reports OpenTelemetry observable gauges for jobs sitting in River's
+// database, which middleware can't see because they're not passing through
+// insert or work:
+//
+//   - `river.job_count`: Number of jobs by queue and state.
+//   - `river.job_oldest_available_age`: Age of the oldest available job by
+//     queue, measured from when it became available to be worked.
+//
+// Gauges are observed by querying the database each time metrics are
+// collected, so their freshness and the load they put on the database are
+// governed by the collection interval of the MeterProvider's reader.
+//
+// Only queues and states that currently have jobs are reported.
+type Collector struct {
+	config       *CollectorConfig
+	exec         riverdriver.Executor
+	registration metric.Registration
+	sql          string
+}
+
+// NewCollector initializes a new River OpenTelemetry job collector that
+// queries jobs through the given driver, which should be the same one that's
+// used to initialize a River client. Call Close to stop it reporting.
+//
+// config may be nil.
+func NewCollector[TTx any](driver riverdriver.Driver[TTx], config *CollectorConfig) *Collector {
+	if config == nil {
+		config = &CollectorConfig{}
+	}
+
+	durationUnit := cmp.Or(config.DurationUnit, "s")
+	if durationUnit != "ms" && durationUnit != "s" {
+		panic("duration unit must be one of ms or s")
+	}
+
+	states := config.States
+	if len(states) == 0 {
+		states = slices.DeleteFunc(rivertype.JobStates(), func(state rivertype.JobState) bool {
+			return state == rivertype.JobStateCancelled || state == rivertype.JobStateCompleted
+		})
+	}
+	for _, state := range states {
+		if !slices.Contains(rivertype.JobStates(), state) {
+			panic("invalid job state: " + string(state))
+		}
+	}
+
+	meterProvider := otel.GetMeterProvider()
+	if config.MeterProvider != nil {
+		meterProvider = config.MeterProvider
+	}
+
+	meter := meterProvider.Meter(name)
+
+	var (
+		jobCount              = mustInt64ObservableGauge(meter, prefix+"job_count", metric.WithDescription("Number of jobs by queue and state"), metric.WithUnit("{job}"))
+		jobOldestAvailableAge = mustFloat64ObservableGauge(meter, prefix+"job_oldest_available_age", metric.WithDescription("Age of the oldest available job by queue"), metric.WithUnit(durationUnit))
+	)
+
+	collector := &Collector{
+		config: config,
+		exec:   driver.GetExecutor(),
+		sql:    jobCountSQL(driver.DatabaseName(), config.Schema, states),
+	}
+
+	registration, err := meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		counts, err := collector.jobCounts(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, count := range counts {
+			observer.ObserveInt64(jobCount, count.Count, metric.WithAttributes(
+				attribute.String("queue", count.Queue),
+				attribute.String("state", string(count.State)),
+			))
+
+			if count.OldestAvailableAge != nil {
+				age := *count.OldestAvailableAge
+				if durationUnit == "ms" {
+					age *= 1000
+				}
+
+				observer.ObserveFloat64(jobOldestAvailableAge, age, metric.WithAttributes(
+					attribute.String("queue", count.Queue),
+				))
+			}
+		}
+
+		return nil
+	}, jobCount, jobOldestAvailableAge)
+	if err != nil {
+		panic(err)
+	}
+
+	collector.registration = registration
+
+	return collector
+}
+
+// Close stops the collector reporting metrics.
+func (c *Collector) Close() error {
+	return c.registration.Unregister()
+}
+
+// Job count for a single queue and state as returned by jobCountSQL.
+type collectorJobCount struct {
+	Count              int64              `json:"count"`
+	OldestAvailableAge *float64           `json:"oldest_available_age"` // in seconds; only set for available jobs
+	Queue              string             `json:"queue"`
+	State              rivertype.JobState `json:"state"`
+}
+
+func (c *Collector) jobCounts(ctx context.Context) ([]*collectorJobCount, error) {
+	var countsJSON string
+	if err := c.exec.QueryRow(ctx, c.sql).Scan(&countsJSON); err != nil {
+		return nil, fmt.Errorf("error querying job counts: %w", err)
+	}
+
+	var counts []*collectorJobCount
+	if err := json.Unmarshal([]byte(countsJSON), &counts); err != nil {
+		return nil, fmt.Errorf("error unmarshaling job counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// jobCountSQL produces a query that returns job counts by queue and state as a
+// single JSON array so that it can be run through an executor's QueryRow.
+// States are inlined because they've already been validated as known job
+// states, which sidesteps differences in how drivers handle array parameters.
+func jobCountSQL(databaseName, schema string, states []rivertype.JobState) string {
+	quotedStates := make([]string, len(states))
+	for i, state := range states {
+		quotedStates[i] = "'" + string(state) + "'"
+	}
+
+	table := "river_job"
+	if schema != "" {
+		table = dbutil.SafeIdentifier(schema) + "." + table
+	}
+
+	if databaseName == "sqlite" {
+		return `
+			SELECT coalesce(json_group_array(json_object('count', count, 'oldest_available_age', oldest_available_age, 'queue', queue, 'state', state)), '[]')
+			FROM (
+				SELECT
+					count(*) AS count,
+					CASE WHEN state = 'available' THEN max(0, (julianday('now') - julianday(min(scheduled_at))) * 86400.0) END AS oldest_available_age,
+					queue,
+					state
+				FROM ` + table + `
+				WHERE state IN (` + strings.Join(quotedStates, ", ") + `)
+				GROUP BY queue, state
+			)`
+	}
+
+	return `
+		SELECT coalesce(json_agg(json_build_object('count', count, 'oldest_available_age', oldest_available_age, 'queue', queue, 'state', state)), '[]')::text
+		FROM (
+			SELECT
+				count(*) AS count,
+				CASE WHEN state = 'available' THEN greatest(0, extract(epoch FROM now() - min(scheduled_at))) END AS oldest_available_age,
+				queue,
+				state
+			FROM ` + table + `
+			WHERE state IN (` + strings.Join(quotedStates, ", ") + `)
+			GROUP BY queue, state
+		) AS job_counts`
+}
+
+func mustFloat64ObservableGauge(meter metric.Meter, name string, options ...metric.Float64ObservableGaugeOption) metric.Float64ObservableGauge {
+	metric, err := meter.Float64ObservableGauge(name, options...)
+	if err != nil {
+		panic(err)
+	}
+	return metric
+}
+
+func mustInt64ObservableGauge(meter metric.Meter, name string, options ...metric.Int64ObservableGaugeOption) metric.Int64ObservableGauge {
+	metric, err := meter.Int64ObservableGauge(name, options...)
+	if err != nil {
+		panic(err)
+	}
+	return metric
+}
@@ -0,0 +1,331 @@
+package otelriver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/riverqueue/river/riverdbtest"
+	"github.com/riverqueue/river/riverdriver"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+	"github.com/riverqueue/river/rivershared/testfactory"
+	"github.com/riverqueue/river/rivertype"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	type testBundle struct {
+		exec         *collectorTestExecutor
+		metricReader *metric.ManualReader
+	}
+
+	setupConfig := func(t *testing.T, config *CollectorConfig) (*Collector, *testBundle) {
+		t.Helper()
+
+		var (
+			exec         = &collectorTestExecutor{countsJSON: "[]"}
+			metricReader = metric.NewManualReader()
+		)
+
+		config.MeterProvider = metric.NewMeterProvider(metric.WithReader(metricReader))
+
+		collector := NewCollector(&collectorTestDriver{databaseName: "postgres", exec: exec}, config)
+		t.Cleanup(func() { require.NoError(t, collector.Close()) })
+
+		return collector, &testBundle{
+			exec:         exec,
+			metricReader: metricReader,
+		}
+	}
+
+	setup := func(t *testing.T) (*Collector, *testBundle) {
+		t.Helper()
+
+		return setupConfig(t, &CollectorConfig{})
+	}
+
+	t.Run("JobCounts", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		bundle.exec.countsJSON = `[
+			{"count": 12, "oldest_available_age": 2.5, "queue": "default", "state": "available"},
+			{"count": 3, "oldest_available_age": null, "queue": "default", "state": "retryable"},
+			{"count": 7, "oldest_available_age": null, "queue": "critical", "state": "scheduled"}
+		]`
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		{
+			metric, metricData := requireMetric[metricdata.Gauge[int64]](t, metrics, "river.job_count")
+			require.Equal(t, "{job}", metric.Unit)
+			require.Len(t, metricData.DataPoints, 3)
+			requireGaugeDataPoint(t, metricData, 12, attribute.String("queue", "default"), attribute.String("state", "available"))
+			requireGaugeDataPoint(t, metricData, 3, attribute.String("queue", "default"), attribute.String("state", "retryable"))
+			requireGaugeDataPoint(t, metricData, 7, attribute.String("queue", "critical"), attribute.String("state", "scheduled"))
+		}
+
+		{
+			metric, metricData := requireMetric[metricdata.Gauge[float64]](t, metrics, "river.job_oldest_available_age")
+			require.Equal(t, "s", metric.Unit)
+			require.Len(t, metricData.DataPoints, 1)
+			requireGaugeDataPoint(t, metricData, 2.5, attribute.String("queue", "default"))
+		}
+
+		// Default states exclude completed and cancelled.
+		require.Contains(t, bundle.exec.sql, "FROM river_job")
+		require.Contains(t, bundle.exec.sql, "'available', 'discarded', 'pending', 'retryable', 'running', 'scheduled'")
+	})
+
+	t.Run("DurationUnitMS", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setupConfig(t, &CollectorConfig{DurationUnit: "ms"})
+
+		bundle.exec.countsJSON = `[{"count": 1, "oldest_available_age": 2.5, "queue": "default", "state": "available"}]`
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		metric, metricData := requireMetric[metricdata.Gauge[float64]](t, metrics, "river.job_oldest_available_age")
+		require.Equal(t, "ms", metric.Unit)
+		requireGaugeDataPoint(t, metricData, 2500.0, attribute.String("queue", "default"))
+	})
+
+	t.Run("SchemaAndStates", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setupConfig(t, &CollectorConfig{
+			Schema: "my_schema",
+			States: []rivertype.JobState{rivertype.JobStateAvailable, rivertype.JobStateDiscarded},
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		require.Contains(t, bundle.exec.sql, `FROM "my_schema".river_job`)
+		require.Contains(t, bundle.exec.sql, "state IN ('available', 'discarded')")
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		bundle.exec.err = errors.New("query error")
+
+		var metrics metricdata.ResourceMetrics
+		require.ErrorContains(t, bundle.metricReader.Collect(ctx, &metrics), "error querying job counts: query error")
+	})
+
+	t.Run("InvalidState", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "invalid job state: not_a_state", func() {
+			NewCollector(&collectorTestDriver{}, &CollectorConfig{
+				States: []rivertype.JobState{"not_a_state"},
+			})
+		})
+	})
+}
+
+// Runs Collector's query against a real Postgres database. Requires a database
+// at TEST_DATABASE_URL or the default local River test database.
+func TestCollectorPostgres(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	type testBundle struct {
+		exec         riverdriver.Executor
+		metricReader *metric.ManualReader
+		schema       string
+	}
+
+	setupConfig := func(t *testing.T, config *CollectorConfig) (*Collector, *testBundle) {
+		t.Helper()
+
+		dbPool, err := pgxpool.New(ctx, riversharedtest.TestDatabaseURL())
+		require.NoError(t, err)
+		t.Cleanup(dbPool.Close)
+
+		var (
+			driver       = riverpgxv5.New(dbPool)
+			metricReader = metric.NewManualReader()
+			schema       = riverdbtest.TestSchema(ctx, t, driver, nil)
+		)
+
+		config.MeterProvider = metric.NewMeterProvider(metric.WithReader(metricReader))
+		config.Schema = schema
+
+		collector := NewCollector(driver, config)
+		t.Cleanup(func() { require.NoError(t, collector.Close()) })
+
+		return collector, &testBundle{
+			exec:         driver.GetExecutor(),
+			metricReader: metricReader,
+			schema:       schema,
+		}
+	}
+
+	setup := func(t *testing.T) (*Collector, *testBundle) {
+		t.Helper()
+
+		return setupConfig(t, &CollectorConfig{})
+	}
+
+	insertJob := func(t *testing.T, bundle *testBundle, queue string, state rivertype.JobState, scheduledAt time.Time) {
+		t.Helper()
+
+		testfactory.Job(ctx, t, bundle.exec, &testfactory.JobOpts{
+			Queue:       &queue,
+			ScheduledAt: &scheduledAt,
+			Schema:      bundle.schema,
+			State:       &state,
+		})
+	}
+
+	t.Run("JobCounts", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		now := time.Now()
+		insertJob(t, bundle, "default", rivertype.JobStateAvailable, now.Add(-1*time.Minute))
+		insertJob(t, bundle, "default", rivertype.JobStateAvailable, now.Add(-10*time.Minute))
+		insertJob(t, bundle, "default", rivertype.JobStateRetryable, now.Add(1*time.Minute))
+		insertJob(t, bundle, "critical", rivertype.JobStateAvailable, now.Add(-30*time.Second))
+		insertJob(t, bundle, "critical", rivertype.JobStateScheduled, now.Add(1*time.Hour))
+		insertJob(t, bundle, "critical", rivertype.JobStateScheduled, now.Add(2*time.Hour))
+
+		// Excluded by default states.
+		insertJob(t, bundle, "default", rivertype.JobStateCompleted, now.Add(-1*time.Hour))
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		{
+			_, metricData := requireMetric[metricdata.Gauge[int64]](t, metrics, "river.job_count")
+			require.Len(t, metricData.DataPoints, 4)
+			requireGaugeDataPoint(t, metricData, 2, attribute.String("queue", "default"), attribute.String("state", "available"))
+			requireGaugeDataPoint(t, metricData, 1, attribute.String("queue", "default"), attribute.String("state", "retryable"))
+			requireGaugeDataPoint(t, metricData, 1, attribute.String("queue", "critical"), attribute.String("state", "available"))
+			requireGaugeDataPoint(t, metricData, 2, attribute.String("queue", "critical"), attribute.String("state", "scheduled"))
+		}
+
+		{
+			_, metricData := requireMetric[metricdata.Gauge[float64]](t, metrics, "river.job_oldest_available_age")
+			require.Len(t, metricData.DataPoints, 2)
+			requireGaugeDataPointInDelta(t, metricData, (10 * time.Minute).Seconds(), 5, attribute.String("queue", "default"))
+			requireGaugeDataPointInDelta(t, metricData, (30 * time.Second).Seconds(), 5, attribute.String("queue", "critical"))
+		}
+	})
+
+	t.Run("OldestAvailableAgeNotNegative", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		// An available job scheduled in the future, which shouldn't produce a
+		// negative age.
+		insertJob(t, bundle, "default", rivertype.JobStateAvailable, time.Now().Add(1*time.Hour))
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		_, metricData := requireMetric[metricdata.Gauge[float64]](t, metrics, "river.job_oldest_available_age")
+		requireGaugeDataPoint(t, metricData, 0.0, attribute.String("queue", "default"))
+	})
+
+	t.Run("States", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setupConfig(t, &CollectorConfig{
+			States: []rivertype.JobState{rivertype.JobStateCompleted},
+		})
+
+		insertJob(t, bundle, "default", rivertype.JobStateAvailable, time.Now())
+		insertJob(t, bundle, "default", rivertype.JobStateCompleted, time.Now())
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		_, metricData := requireMetric[metricdata.Gauge[int64]](t, metrics, "river.job_count")
+		require.Len(t, metricData.DataPoints, 1)
+		requireGaugeDataPoint(t, metricData, 1, attribute.String("queue", "default"), attribute.String("state", "completed"))
+	})
+}
+
+// collectorTestDriver is a driver that returns a fake executor. Only the
+// functions used by Collector are implemented, and others will panic.
+type collectorTestDriver struct {
+	riverdriver.Driver[any]
+
+	databaseName string
+	exec         *collectorTestExecutor
+}
+
+func (d *collectorTestDriver) DatabaseName() string { return d.databaseName }
+
+func (d *collectorTestDriver) GetExecutor() riverdriver.Executor { return d.exec }
+
+// collectorTestExecutor is an executor that returns a canned JSON result from
+// QueryRow. Only the functions used by Collector are implemented, and others
+// will panic.
+type collectorTestExecutor struct {
+	riverdriver.Executor
+
+	countsJSON string
+	err        error
+	sql        string
+}
+
+func (e *collectorTestExecutor) QueryRow(ctx context.Context, sql string, args ...any) riverdriver.Row {
+	e.sql = sql
+	return &collectorTestRow{countsJSON: e.countsJSON, err: e.err}
+}
+
+type collectorTestRow struct {
+	countsJSON string
+	err        error
+}
+
+func (r *collectorTestRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*(dest[0].(*string)) = r.countsJSON //nolint:forcetypeassert
+	return nil
+}
+
+func requireGaugeDataPoint[N int64 | float64](t *testing.T, metricData metricdata.Gauge[N], val N, attrs ...attribute.KeyValue) {
+	t.Helper()
+
+	requireGaugeDataPointInDelta(t, metricData, val, 0.001, attrs...)
+}
+
+func requireGaugeDataPointInDelta[N int64 | float64](t *testing.T, metricData metricdata.Gauge[N], val N, delta float64, attrs ...attribute.KeyValue) {
+	t.Helper()
+
+	wantSet := attribute.NewSet(attrs...)
+	for _, dataPoint := range metricData.DataPoints {
+		if dataPoint.Attributes.Equals(&wantSet) {
+			require.InDelta(t, val, dataPoint.Value, delta)
+			return
+		}
+	}
+	require.FailNow(t, "no data point found with attributes", "%v", attrs)
+}
@@ -3,15 +3,24 @@ package otelriver
 import (
 	"cmp"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
@@ -26,10 +35,110 @@ const (
 
 	// Prefix added to than names of all emitted metrics and traces.
 	prefix = "river."
+
+	// Default maximum number of inserted job IDs recorded on insert_many spans.
+	insertManyMaxJobIDsDefault = 100
 )
 
+// A span that doesn't record anything, used in place of a real span for
+// operations that SpanSampler decided not to sample.
+var noopSpan = trace.SpanFromContext(context.Background()) //nolint:gochecknoglobals
+
+// Names of histograms emitted by the middleware, for which bucket boundaries
+// may be configured through MiddlewareConfig.HistogramBucketBoundaries.
+var histogramNames = []string{ //nolint:gochecknoglobals
+	"messaging.client.operation.duration",
+	"messaging.process.duration",
+	prefix + "insert_many_duration_histogram",
+	prefix + "job_get_available_count",
+	prefix + "job_get_available_duration",
+	prefix + "periodic_job_enqueue_delay",
+	prefix + "queue_latency",
+	prefix + "work_duration_histogram",
+}
+
+// AttributePath declaratively maps a value in a job's args or metadata to an
+// attribute, for use with MiddlewareConfig.AttributePaths.
+type AttributePath struct {
+	// IncludeInMetrics causes the attribute to be included on metrics in
+	// addition to spans (subject to MetricAttributeFilter). Make sure to keep
+	// attribute cardinality in mind when including values in metrics.
+	IncludeInMetrics bool
+
+	// Key is the key of the produced attribute.
+	Key string
+
+	// Path is a path to the value within a job's args or metadata in gjson
+	// syntax like "tenant_id" or "workflow.id". String, number, and boolean
+	// values produce attributes of the equivalent type, while objects and
+	// arrays produce their raw JSON as a string. No attribute is produced if
+	// the path doesn't exist.
+	Path string
+
+	// Source is the job property that Path is looked up in. Must be one of
+	// "args" or "metadata". Defaults to "args".
+	Source string
+}
+
+// attribute produces an attribute from the value at the path in either args or
+// metadata, depending on the configured source. Returns false if the path
+// doesn't exist.
+func (p *AttributePath) attribute(encodedArgs, metadata []byte) (attribute.KeyValue, bool) {
+	data := encodedArgs
+	if p.Source == "metadata" {
+		data = metadata
+	}
+
+	res := gjson.GetBytes(data, p.Path)
+	switch res.Type {
+	case gjson.False, gjson.True:
+		return attribute.Bool(p.Key, res.Bool()), true
+	case gjson.Number:
+		if res.Num == math.Trunc(res.Num) {
+			return attribute.Int64(p.Key, res.Int()), true
+		}
+		return attribute.Float64(p.Key, res.Num), true
+	case gjson.String:
+		return attribute.String(p.Key, res.Str), true
+	case gjson.JSON:
+		return attribute.String(p.Key, res.Raw), true
+	case gjson.Null:
+		fallthrough
+	default:
+		return attribute.KeyValue{}, false
+	}
+}
+
 // MiddlewareConfig is configuration for River's OpenTelemetry middleware.
 type MiddlewareConfig struct {
+	// AttributesFunc is an optional function that returns custom attributes
+	// derived from a job being worked. They're added to work spans along with
+	// work metrics (subject to MetricAttributeFilter).
+	//
+	// Make sure to keep attribute cardinality in mind when returning values
+	// that'll be used in metrics.
+	AttributesFunc func(job *rivertype.JobRow) []attribute.KeyValue
+
+	// AttributePaths declaratively maps values in the args or metadata of
+	// jobs to attributes, like a `tenant_id` or `workflow_id` argument. On
+	// work, attributes are added to work spans and optionally to work metrics.
+	// On insert, they're added to per-job insert spans (see
+	// EnableInsertJobSpans).
+	AttributePaths []AttributePath
+
+	// DurationMetrics selects which instruments insert and work durations are
+	// recorded to. Durations have traditionally been recorded to both a gauge
+	// (`river.insert_many_duration` and `river.work_duration`) and a histogram
+	// (`river.insert_many_duration_histogram` and
+	// `river.work_duration_histogram`), which doubles export volume, and a
+	// gauge's last value isn't very meaningful for jobs running concurrently.
+	//
+	// Must be one of "both", "gauge", or "histogram". Defaults to "both" for
+	// backward compatibility, but "histogram" is recommended, and gauges may
+	// be removed in a future version. To migrate, move dashboards and alerts
+	// from gauges to the equivalent histograms, then set "histogram".
+	DurationMetrics string
+
 	// DurationUnit selects the unit in which duration metrics like
 	// `river.work_duration` are emitted.
 	//
@@ -39,6 +148,26 @@ type MiddlewareConfig struct {
 	// are constrained to seconds by specification.
 	DurationUnit string
 
+	// EnableBaggagePropagation injects W3C baggage into job metadata on insert
+	// and extracts it on work, restoring it into the context that's passed
+	// down to the worker. This lets business context like tenant or request
+	// IDs set by the code that enqueued a job follow it to where it's worked,
+	// and be picked up by spans and logs emitted there.
+	//
+	// May be used with or without EnableTracePropagation.
+	EnableBaggagePropagation bool
+
+	// EnableInsertJobSpans emits a child producer span for each job in an
+	// inserted batch in addition to the `river.insert_many` span for the batch
+	// as a whole. Each `river.insert` span carries attributes for its job's
+	// kind, queue, priority, and scheduled time, along with its ID and whether
+	// it was skipped as a unique duplicate after insertion.
+	//
+	// When used with EnableTracePropagation, it's each job's own span that's
+	// injected into its metadata, so work spans link back to the precise
+	// insertion of their job rather than to the batch.
+	EnableInsertJobSpans bool
+
 	// EnableSemanticMetrics emits metrics compliant with OpenTelemetry's
 	// "semantic conventions" for messaging clients:
 	//
@@ -48,33 +177,245 @@ type MiddlewareConfig struct {
 	// metric names, with attributes differentiating them.
 	EnableSemanticMetrics bool
 
+	// EnableSemanticSpans names spans and sets attributes on them in
+	// compliance with OpenTelemetry's "semantic conventions" for messaging
+	// spans:
+	//
+	// https://opentelemetry.io/docs/specs/semconv/messaging/messaging-spans/
+	//
+	// Work spans are named `process <queue>` and batch insert spans are named
+	// `send <queue>` (or just `send` for a batch spanning multiple queues).
+	// Spans emitted by EnableInsertJobSpans are named `create <queue>`. This
+	// lets APM tools that understand the conventions recognize River as a
+	// messaging system. River-specific attributes like `kind` and `attempt`
+	// are still set alongside `messaging.*` ones.
+	//
+	// Takes precedence over EnableWorkSpanJobKindSuffix.
+	EnableSemanticSpans bool
+
 	// EnableTracePropagation injects W3C trace context (traceparent/tracestate)
 	// into job metadata on insert and extracts it on work, adding a span link
-	// from the work span back to the span that enqueued the job.
+	// from the work span back to the span that enqueued the job (or making it
+	// the work span's parent, depending on TracePropagationMode).
 	EnableTracePropagation bool
 
 	// EnableWorkSpanJobKindSuffix appends the job kind a suffix to work spans
 	// so they look like `river.work/my_job` instead of `river.work`.
 	EnableWorkSpanJobKindSuffix bool
 
+	// HistogramBucketBoundaries are explicit bucket boundaries for histograms
+	// keyed by metric name, like:
+	//
+	//	map[string][]float64{
+	//		"river.work_duration_histogram": {0.001, 0.005, 0.01, 0.1, 1, 60, 600, 3600},
+	//	}
+	//
+	// Boundaries are given to the MeterProvider as instrument advice, so they
+	// take effect without configuring a view, but a view still takes
+	// precedence if there is one. Duration boundaries are in DurationUnit.
+	// Histograms without boundaries use the MeterProvider's defaults.
+	//
+	// Keys should be the name of a histogram emitted by the middleware
+	// (`river.insert_many_duration_histogram`, `river.job_get_available_count`,
+	// `river.job_get_available_duration`, `river.periodic_job_enqueue_delay`,
+	// `river.queue_latency`, `river.work_duration_histogram`, or with
+	// EnableSemanticMetrics, `messaging.client.operation.duration` and
+	// `messaging.process.duration`). Any key prefixed with `river.` is
+	// accepted so that boundaries can be given for histograms of metrics
+	// emitted by newer versions of River, but keys outside that namespace
+	// must be one of the above. Boundaries must be strictly increasing.
+	//
+	// Advice only supports explicit bucket histograms. For exponential
+	// histograms, configure an aggregation selector or view on the
+	// MeterProvider instead.
+	HistogramBucketBoundaries map[string][]float64
+
+	// InsertAttributesFunc is an optional function that returns custom
+	// attributes derived from a job being inserted. They're added to per-job
+	// insert spans, so this option only has an effect when used with
+	// EnableInsertJobSpans.
+	InsertAttributesFunc func(params *rivertype.JobInsertParams) []attribute.KeyValue
+
+	// InsertManyMaxJobIDs is the maximum number of inserted job IDs that are
+	// recorded in the `ids` attribute of `river.insert_many` spans. IDs make it
+	// possible to jump from the trace of a request to the jobs that it
+	// inserted, but may make spans large for very big batches, so they're
+	// capped. IDs beyond the cap are omitted and the span's `ids_truncated`
+	// attribute is set.
+	//
+	// Defaults to 100. Set to -1 to disable recording job IDs.
+	InsertManyMaxJobIDs int
+
+	// LoggerProvider is an optional LoggerProvider used to emit log records
+	// for job lifecycle events: a job starting work, failing, snoozing, being
+	// cancelled, or being discarded after exhausting its attempts. Records are
+	// emitted with the context of the job's work span so that backends can
+	// correlate them with its trace.
+	//
+	// Logs are only emitted when this is set. Unlike MeterProvider and
+	// TracerProvider, it doesn't fall back to a global provider.
+	LoggerProvider log.LoggerProvider
+
 	// MeterProvider is a MeterProvider to base metrics on. May be left as nil
 	// to use the default global provider.
 	MeterProvider metric.MeterProvider
 
+	// MetricAttributeFilter is an optional filter that decides which
+	// attributes are included on emitted metrics. Attributes it rejects are
+	// omitted from metrics, but still set on spans. Use it to control metric
+	// cardinality, like to leave out a job's `tag` attribute if tags contain
+	// high cardinality values like customer IDs.
+	//
+	// Use attribute.NewAllowKeysFilter to produce an allowlist of attribute
+	// keys or attribute.NewDenyKeysFilter to produce a denylist.
+	//
+	// Has no effect on metrics emitted by EnableSemanticMetrics because their
+	// attributes are constrained by specification.
+	MetricAttributeFilter attribute.Filter
+
+	// Propagator is a TextMapPropagator used to inject context into job
+	// metadata on insert and extract it on work when EnableTracePropagation or
+	// EnableBaggagePropagation are on. May be left as nil to use the default
+	// global propagator. Useful for services configured with propagation
+	// formats other than W3C like B3, Jaeger, or X-Ray.
+	//
+	// W3C trace context and baggage propagators are always included according
+	// to EnableTracePropagation and EnableBaggagePropagation so that those work
+	// even if no propagator is configured. When both W3C and another format
+	// are present in a job's metadata, the other format takes precedence.
+	//
+	// Propagator is only used with EnableTracePropagation, and any W3C baggage
+	// it'd propagate (like when it's a composite including the baggage
+	// propagator, as global propagators commonly are) is left out unless
+	// EnableBaggagePropagation is also on, so baggage is never stored in job
+	// metadata without opting into it.
+	Propagator propagation.TextMapPropagator
+
+	// SpanSampler is an optional function that decides whether spans are
+	// recorded for a batch insert or a job being worked. It can be used to
+	// drop spans for high volume job kinds like heartbeats while keeping them
+	// for rare, important ones. Metrics and logs are recorded regardless of
+	// its decision.
+	//
+	// Spans are always recorded for operations that fail, even if SpanSampler
+	// returned false. Because failure isn't known until an operation is
+	// finished, these spans are started after the fact, backdated to when the
+	// operation began. Spans started by the worker itself won't be children
+	// of a span recorded this way.
+	//
+	// When an operation isn't sampled, the context passed down to the worker
+	// or insert carries a span context marked as not sampled, so that spans
+	// started beneath it are also dropped by parent-based samplers like the
+	// SDK's default.
+	//
+	// May be left nil to record spans for all operations.
+	SpanSampler func(params *SpanSamplerParams) bool
+
+	// TracePropagationMetadataPath is a path to a JSON object within job
+	// metadata under which propagated fields like `traceparent`, `tracestate`,
+	// and `baggage` are stored. For example, a path of "otel" stores them like
+	// `{"otel":{"traceparent":"..."}}`. Nested paths like "telemetry.otel" are
+	// supported, and use the same syntax as gjson/sjson.
+	//
+	// Fields are always read from the top level of metadata in addition to the
+	// configured path so that jobs that were inserted before a path was
+	// configured still propagate. Where a field is present in both places, the
+	// one at the configured path takes precedence.
+	//
+	// Defaults to empty, which stores fields at the top level of metadata.
+	TracePropagationMetadataPath string
+
+	// TracePropagationMode selects how work spans are related to the span that
+	// enqueued their job when EnableTracePropagation is on.
+	//
+	// Must be one of:
+	//
+	// * "link": Work spans are started as new roots with a link to the span
+	//   that enqueued their job. Jobs are async by nature and may be worked
+	//   minutes, hours, or even days after they're enqueued, which can lead to
+	//   strange looking traces if a direct parent relationship is used.
+	// * "parent": Work spans are started as children of the span that enqueued
+	//   their job, making them show up inline in the same trace.
+	// * "parent_if_recent": Work spans are started as children of the span that
+	//   enqueued their job if it's being worked for the first time and within
+	//   TracePropagationParentMaxDelay of being inserted. Otherwise, they're
+	//   linked. Useful so that short, request-scoped jobs show up inline in
+	//   their request's trace while delayed or retried jobs still get links.
+	//
+	// Defaults to "link".
+	TracePropagationMode string
+
+	// TracePropagationParentMaxDelay is the maximum time between a job's
+	// insertion and the start of its work for its work span to be started as
+	// a child of the span that enqueued it. Required with a
+	// TracePropagationMode of "parent_if_recent" and ignored otherwise.
+	TracePropagationParentMaxDelay time.Duration
+
 	// TracerProvider is a TracerProvider to base traces on. May be left as nil
 	// to use the default global provider.
 	TracerProvider trace.TracerProvider
 }
 
+// SpanSamplerParams are parameters for MiddlewareConfig.SpanSampler.
+type SpanSamplerParams struct {
+	// InsertParams are jobs being inserted. Only set for an Operation of
+	// "insert_many".
+	InsertParams []*rivertype.JobInsertParams
+
+	// Job is the job being worked, including its kind, queue, and attempt.
+	// Only set for an Operation of "work".
+	Job *rivertype.JobRow
+
+	// Operation is the operation being sampled, either "insert_many" or
+	// "work".
+	Operation string
+}
+
 // Middleware is a River middleware and hook that emits OpenTelemetry traces and
 // metrics.
 type Middleware struct {
 	river.PluginDefaults
 
-	config  *MiddlewareConfig
-	meter   metric.Meter
-	metrics middlewareMetrics
-	tracer  trace.Tracer
+	config     *MiddlewareConfig
+	logger     log.Logger // nil unless LoggerProvider is set
+	meter      metric.Meter
+	metrics    middlewareMetrics
+	propagator propagation.TextMapPropagator // nil unless trace or baggage propagation is enabled
+	tracer     trace.Tracer
+
+	riverMetrics riverMetricInstruments
+}
+
+// Timings recorded by hooks for an insert or work operation, which are carried
+// in context from InsertMany or Work to the hooks that run inside it, then
+// added to the operation's span once it's finished. Hooks record timestamps
+// instead of adding span events directly so that the events can still be
+// added to spans started after the fact (see SpanSampler).
+type hookTimings struct {
+	insertBeginAt time.Time
+	workBeginAt   time.Time
+	workEndAt     time.Time
+}
+
+type hookTimingsContextKey struct{}
+
+// A periodic job being inserted by River's periodic job enqueuer.
+type periodicInsert struct {
+	index         int // index in inserted batch
+	params        *rivertype.JobInsertParams
+	periodicJobID string // empty unless the periodic job was configured with an ID
+	span          trace.Span
+}
+
+func (i *periodicInsert) attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("kind", i.params.Kind),
+		attribute.String("queue", i.params.Queue),
+	}
+	if i.periodicJobID != "" {
+		attrs = append(attrs, attribute.String("periodic_job_id", i.periodicJobID))
+	}
+	return attrs
 }
 
 // Bundle of metrics associated with a middleware.
@@ -83,12 +424,15 @@ type middlewareMetrics struct {
 	insertManyCount                  metric.Int64Counter
 	insertManyDuration               metric.Float64Gauge
 	insertManyDurationHistogram      metric.Float64Histogram
-	jobGetAvailableDuration          metric.Float64Histogram
-	jobGetAvailableCount             metric.Int64Histogram
+	jobDiscardedCount                metric.Int64Counter
 	messagingClientConsumedMessages  metric.Int64Counter
 	messagingClientOperationDuration metric.Float64Histogram
 	messagingClientSentMessages      metric.Int64Counter
 	messagingProcessDuration         metric.Float64Histogram
+	metricEmitUnmappedCount          metric.Int64Counter
+	periodicJobEnqueueCount          metric.Int64Counter
+	periodicJobEnqueueDelay          metric.Float64Histogram
+	queueLatency                     metric.Float64Histogram
 	workCount                        metric.Int64Counter
 	workDuration                     metric.Float64Gauge
 	workDurationHistogram            metric.Float64Histogram
@@ -107,6 +451,43 @@ func NewMiddleware(config *MiddlewareConfig) *Middleware {
 		panic("duration unit must be one of ms or s")
 	}
 
+	durationMetrics := cmp.Or(config.DurationMetrics, "both")
+	if durationMetrics != "both" && durationMetrics != "gauge" && durationMetrics != "histogram" {
+		panic("duration metrics must be one of both, gauge, or histogram")
+	}
+
+	for histogramName, boundaries := range config.HistogramBucketBoundaries {
+		// River may emit metrics the middleware doesn't know about up front, so
+		// any key in its namespace is allowed.
+		if !strings.HasPrefix(histogramName, prefix) && !slices.Contains(histogramNames, histogramName) {
+			panic("histogram bucket boundaries given for unknown histogram: " + histogramName)
+		}
+		for i := 1; i < len(boundaries); i++ {
+			if boundaries[i] <= boundaries[i-1] {
+				panic("histogram bucket boundaries must be strictly increasing: " + histogramName)
+			}
+		}
+	}
+
+	for _, attrPath := range config.AttributePaths {
+		if attrPath.Key == "" || attrPath.Path == "" {
+			panic("attribute path must have a key and path")
+		}
+		if attrPath.Source != "" && attrPath.Source != "args" && attrPath.Source != "metadata" {
+			panic("attribute path source must be one of args or metadata")
+		}
+	}
+
+	switch config.TracePropagationMode {
+	case "", "link", "parent":
+	case "parent_if_recent":
+		if config.TracePropagationParentMaxDelay <= 0 {
+			panic("trace propagation parent max delay must be greater than zero with trace propagation mode parent_if_recent")
+		}
+	default:
+		panic("trace propagation mode must be one of link, parent, or parent_if_recent")
+	}
+
 	meterProvider := otel.GetMeterProvider()
 	if config.MeterProvider != nil {
 		meterProvider = config.MeterProvider
@@ -117,42 +498,115 @@ func NewMiddleware(config *MiddlewareConfig) *Middleware {
 		tracerProvider = config.TracerProvider
 	}
 
+	textMapPropagator := otel.GetTextMapPropagator()
+	if config.Propagator != nil {
+		textMapPropagator = config.Propagator
+	}
+
 	meter := meterProvider.Meter(name)
 
 	metrics := middlewareMetrics{
 		// See unit guidelines:
 		//
 		// https://opentelemetry.io/docs/specs/semconv/general/metrics/#instrument-units
-		insertCount:                 mustInt64Counter(meter, prefix+"insert_count", metric.WithDescription("Number of jobs inserted"), metric.WithUnit("{job}")),
-		insertManyCount:             mustInt64Counter(meter, prefix+"insert_many_count", metric.WithDescription("Number of job batches inserted (all jobs are inserted in a batch, but batches may be one job)"), metric.WithUnit("{job_batch}")),
-		insertManyDuration:          mustFloat64Gauge(meter, prefix+"insert_many_duration", metric.WithDescription("Duration of job batch insertion"), metric.WithUnit(durationUnit)),
-		insertManyDurationHistogram: mustFloat64Histogram(meter, prefix+"insert_many_duration_histogram", metric.WithDescription("Duration of job batch insertion (histogram)"), metric.WithUnit(durationUnit)),
-		jobGetAvailableDuration:     mustFloat64Histogram(meter, prefix+"job_get_available_duration", metric.WithDescription("Duration of successful JobGetAvailable calls"), metric.WithUnit(durationUnit)),
-		jobGetAvailableCount:        mustInt64Histogram(meter, prefix+"job_get_available_count", metric.WithDescription("Number of jobs locked by successful JobGetAvailable calls"), metric.WithUnit("{job}")),
-		workCount:                   mustInt64Counter(meter, prefix+"work_count", metric.WithDescription("Number of jobs worked"), metric.WithUnit("{job}")),
-		workDuration:                mustFloat64Gauge(meter, prefix+"work_duration", metric.WithDescription("Duration of job being worked"), metric.WithUnit(durationUnit)),
-		workDurationHistogram:       mustFloat64Histogram(meter, prefix+"work_duration_histogram", metric.WithDescription("Duration of job being worked (histogram)"), metric.WithUnit(durationUnit)),
+		insertCount:             mustInt64Counter(meter, prefix+"insert_count", metric.WithDescription("Number of jobs inserted"), metric.WithUnit("{job}")),
+		insertManyCount:         mustInt64Counter(meter, prefix+"insert_many_count", metric.WithDescription("Number of job batches inserted (all jobs are inserted in a batch, but batches may be one job)"), metric.WithUnit("{job_batch}")),
+		jobDiscardedCount:       mustInt64Counter(meter, prefix+"job_discarded_count", metric.WithDescription("Number of jobs discarded after exhausting their attempts"), metric.WithUnit("{job}")),
+		metricEmitUnmappedCount: mustInt64Counter(meter, prefix+"metric_emit_unmapped_count", metric.WithDescription("Number of metrics emitted by River that couldn't be mapped to instruments"), metric.WithUnit("{metric}")),
+		periodicJobEnqueueCount: mustInt64Counter(meter, prefix+"periodic_job_enqueue_count", metric.WithDescription("Number of periodic jobs enqueued"), metric.WithUnit("{job}")),
+		periodicJobEnqueueDelay: mustFloat64Histogram(meter, config.HistogramBucketBoundaries, prefix+"periodic_job_enqueue_delay", metric.WithDescription("Time between when a periodic job was scheduled to run and when it was enqueued"), metric.WithUnit(durationUnit)),
+		queueLatency:            mustFloat64Histogram(meter, config.HistogramBucketBoundaries, prefix+"queue_latency", metric.WithDescription("Time between a job becoming available to be worked and being worked"), metric.WithUnit(durationUnit)),
+		workCount:               mustInt64Counter(meter, prefix+"work_count", metric.WithDescription("Number of jobs worked"), metric.WithUnit("{job}")),
+	}
+
+	// Duration instruments are left nil if not selected by DurationMetrics.
+	if durationMetrics == "both" || durationMetrics == "gauge" {
+		metrics.insertManyDuration = mustFloat64Gauge(meter, prefix+"insert_many_duration", metric.WithDescription("Duration of job batch insertion"), metric.WithUnit(durationUnit))
+		metrics.workDuration = mustFloat64Gauge(meter, prefix+"work_duration", metric.WithDescription("Duration of job being worked"), metric.WithUnit(durationUnit))
+	}
+	if durationMetrics == "both" || durationMetrics == "histogram" {
+		metrics.insertManyDurationHistogram = mustFloat64Histogram(meter, config.HistogramBucketBoundaries, prefix+"insert_many_duration_histogram", metric.WithDescription("Duration of job batch insertion (histogram)"), metric.WithUnit(durationUnit))
+		metrics.workDurationHistogram = mustFloat64Histogram(meter, config.HistogramBucketBoundaries, prefix+"work_duration_histogram", metric.WithDescription("Duration of job being worked (histogram)"), metric.WithUnit(durationUnit))
 	}
 
 	if config.EnableSemanticMetrics {
 		metrics.messagingClientConsumedMessages = mustInt64Counter(meter, "messaging.client.consumed.messages", metric.WithDescription("Number of messages that were delivered to the application."))
-		metrics.messagingClientOperationDuration = mustFloat64Histogram(meter, "messaging.client.operation.duration", metric.WithDescription("Duration of messaging operation initiated by a producer or consumer client."), metric.WithUnit(durationUnit))
+		metrics.messagingClientOperationDuration = mustFloat64Histogram(meter, config.HistogramBucketBoundaries, "messaging.client.operation.duration", metric.WithDescription("Duration of messaging operation initiated by a producer or consumer client."), metric.WithUnit(durationUnit))
 		metrics.messagingClientSentMessages = mustInt64Counter(meter, "messaging.client.sent.messages", metric.WithDescription("Number of messages producer attempted to send to the broker."))
-		metrics.messagingProcessDuration = mustFloat64Histogram(meter, "messaging.process.duration", metric.WithDescription("Duration of processing operation."), metric.WithUnit(durationUnit))
+		metrics.messagingProcessDuration = mustFloat64Histogram(meter, config.HistogramBucketBoundaries, "messaging.process.duration", metric.WithDescription("Duration of processing operation."), metric.WithUnit(durationUnit))
+	}
+
+	var propagator propagation.TextMapPropagator
+	if config.EnableTracePropagation || config.EnableBaggagePropagation {
+		var propagators []propagation.TextMapPropagator
+		if config.EnableTracePropagation {
+			propagators = append(propagators, propagation.TraceContext{})
+		}
+		if config.EnableBaggagePropagation {
+			propagators = append(propagators, propagation.Baggage{})
+		}
+
+		// The configured or global propagator is for trace formats, so it's
+		// only used with trace propagation. It's commonly a composite that
+		// includes W3C baggage, which is omitted unless baggage propagation is
+		// also enabled so that baggage isn't persisted to job metadata without
+		// opting in. Goes last so that it takes precedence on extract.
+		if config.EnableTracePropagation {
+			if config.EnableBaggagePropagation {
+				propagators = append(propagators, textMapPropagator)
+			} else {
+				propagators = append(propagators, &fieldOmittingPropagator{
+					omittedFields:     propagation.Baggage{}.Fields(),
+					TextMapPropagator: textMapPropagator,
+				})
+			}
+		}
+
+		propagator = propagation.NewCompositeTextMapPropagator(propagators...)
+	}
+
+	var logger log.Logger
+	if config.LoggerProvider != nil {
+		logger = config.LoggerProvider.Logger(name)
 	}
 
 	return &Middleware{
-		config:  config,
-		meter:   meter,
-		metrics: metrics,
-		tracer:  tracerProvider.Tracer(name),
+		config:     config,
+		logger:     logger,
+		meter:      meter,
+		metrics:    metrics,
+		propagator: propagator,
+		tracer:     tracerProvider.Tracer(name),
 	}
 }
 
 func (m *Middleware) InsertMany(ctx context.Context, manyParams []*rivertype.JobInsertParams, doInner func(ctx context.Context) ([]*rivertype.JobInsertResult, error)) ([]*rivertype.JobInsertResult, error) {
-	ctx, span := m.tracer.Start(ctx, prefix+"insert_many",
-		trace.WithSpanKind(trace.SpanKindProducer))
-	defer span.End()
+	spanName := prefix + "insert_many"
+	startOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
+	if m.config.EnableSemanticSpans {
+		// Destination is only set if all jobs in the batch share a queue.
+		var queue string
+		if len(manyParams) > 0 && !slices.ContainsFunc(manyParams, func(p *rivertype.JobInsertParams) bool { return p.Queue != manyParams[0].Queue }) {
+			queue = manyParams[0].Queue
+		}
+
+		spanName = strings.TrimSpace("send " + queue)
+		semanticAttrs := semanticSpanAttributes("insert_many", "send", queue)
+		if len(manyParams) > 1 {
+			semanticAttrs = append(semanticAttrs, attribute.Int("messaging.batch.message_count", len(manyParams)))
+		}
+		startOpts = append(startOpts, trace.WithAttributes(semanticAttrs...))
+	}
+
+	var (
+		parentCtx = ctx
+		sampled   = m.spanSampled(&SpanSamplerParams{InsertParams: manyParams, Operation: "insert_many"})
+		span      = noopSpan
+	)
+	if sampled {
+		ctx, span = m.tracer.Start(ctx, spanName, startOpts...)
+	}
+	defer func() { span.End() }()
 
 	attrs := []attribute.KeyValue{
 		attribute.String("status", ""), // replaced below
@@ -164,12 +618,49 @@ func (m *Middleware) InsertMany(ctx context.Context, manyParams []*rivertype.Job
 		err       error
 		insertRes []*rivertype.JobInsertResult
 		panicked  = true // set to false if program leaves normally
+		timings   = &hookTimings{}
+	)
+	var (
+		jobSpans        []trace.Span
+		periodicInserts []*periodicInsert
 	)
 	defer func() {
 		duration := m.durationInPreferredUnit(time.Since(begin))
 
+		// Failed operations always get a span, even if not sampled.
+		if !sampled && (panicked || err != nil) {
+			_, span = m.tracer.Start(parentCtx, spanName, append(startOpts, trace.WithTimestamp(begin))...)
+		}
+
+		if !timings.insertBeginAt.IsZero() {
+			span.AddEvent(prefix+"insert_begin", trace.WithTimestamp(timings.insertBeginAt))
+		}
+
 		setStatus(attrs, statusIndex, span, panicked, err)
 
+		for i, jobSpan := range jobSpans {
+			jobAttrs := []attribute.KeyValue{
+				attribute.String("status", ""), // replaced below
+			}
+			setStatus(jobAttrs, 0, jobSpan, panicked, err)
+
+			// Results are returned in the same order as params.
+			if i < len(insertRes) && insertRes[i] != nil {
+				if insertRes[i].Job != nil {
+					jobAttrs = append(jobAttrs, attribute.Int64("id", insertRes[i].Job.ID))
+					if m.config.EnableSemanticSpans {
+						jobAttrs = append(jobAttrs, attribute.String("messaging.message.id", strconv.FormatInt(insertRes[i].Job.ID, 10)))
+					}
+				}
+				jobAttrs = append(jobAttrs, attribute.Bool("unique_skipped_as_duplicate", insertRes[i].UniqueSkippedAsDuplicate))
+			}
+
+			jobSpan.SetAttributes(jobAttrs...)
+			jobSpan.End()
+		}
+
+		m.finishPeriodicInserts(ctx, periodicInserts, insertRes, begin, panicked, err)
+
 		var skipped int64
 		for _, r := range insertRes {
 			if r != nil && r.UniqueSkippedAsDuplicate {
@@ -189,9 +680,26 @@ func (m *Middleware) InsertMany(ctx context.Context, manyParams []*rivertype.Job
 			attribute.StringSlice("kinds", kinds),
 			attribute.Int64("unique_skipped_as_duplicate_count", skipped),
 		)
+		if maxJobIDs := m.insertManyMaxJobIDs(); maxJobIDs > 0 && len(insertRes) > 0 {
+			ids := make([]int64, 0, min(len(insertRes), maxJobIDs))
+			for _, r := range insertRes {
+				if r == nil || r.Job == nil {
+					continue
+				}
+				if len(ids) >= maxJobIDs {
+					span.SetAttributes(attribute.Bool("ids_truncated", true))
+					break
+				}
+				ids = append(ids, r.Job.ID)
+			}
+			span.SetAttributes(attribute.Int64Slice("ids", ids))
+		}
+		if m.config.EnableSemanticSpans && len(insertRes) == 1 && insertRes[0] != nil && insertRes[0].Job != nil {
+			span.SetAttributes(attribute.String("messaging.message.id", strconv.FormatInt(insertRes[0].Job.ID, 10)))
+		}
 
 		// This allocates a new slice, so make sure to do it as few times as possible.
-		measurementOpt := metric.WithAttributes(attrs...)
+		measurementOpt := m.metricAttributes(attrs...)
 
 		// Partition insert_count by unique_skipped_as_duplicate so the
 		// metric shows how many of the submitted jobs were dropped by
@@ -199,15 +707,19 @@ func (m *Middleware) InsertMany(ctx context.Context, manyParams []*rivertype.Job
 		// still equals len(manyParams).
 		if inserted := int64(len(manyParams)) - skipped; inserted > 0 {
 			m.metrics.insertCount.Add(ctx, inserted,
-				metric.WithAttributes(append(attrs, attribute.Bool("unique_skipped_as_duplicate", false))...))
+				m.metricAttributes(append(attrs, attribute.Bool("unique_skipped_as_duplicate", false))...))
 		}
 		if skipped > 0 {
 			m.metrics.insertCount.Add(ctx, skipped,
-				metric.WithAttributes(append(attrs, attribute.Bool("unique_skipped_as_duplicate", true))...))
+				m.metricAttributes(append(attrs, attribute.Bool("unique_skipped_as_duplicate", true))...))
 		}
 		m.metrics.insertManyCount.Add(ctx, 1, measurementOpt)
-		m.metrics.insertManyDuration.Record(ctx, duration, measurementOpt)
-		m.metrics.insertManyDurationHistogram.Record(ctx, duration, measurementOpt)
+		if m.metrics.insertManyDuration != nil {
+			m.metrics.insertManyDuration.Record(ctx, duration, measurementOpt)
+		}
+		if m.metrics.insertManyDurationHistogram != nil {
+			m.metrics.insertManyDurationHistogram.Record(ctx, duration, measurementOpt)
+		}
 
 		if m.config.EnableSemanticMetrics {
 			measurementOpt := metric.WithAttributes(
@@ -220,51 +732,125 @@ func (m *Middleware) InsertMany(ctx context.Context, manyParams []*rivertype.Job
 		}
 	}()
 
-	if m.config.EnableTracePropagation {
-		for i := range manyParams {
-			manyParams[i].Metadata = injectTraceContext(ctx, manyParams[i].Metadata)
+	if sampled && m.config.EnableInsertJobSpans {
+		jobSpans = make([]trace.Span, 0, len(manyParams))
+	}
+
+	for i, params := range manyParams {
+		jobCtx := ctx
+
+		if sampled && m.config.EnableInsertJobSpans {
+			jobSpanName := prefix + "insert"
+			if m.config.EnableSemanticSpans {
+				jobSpanName = "create " + params.Queue
+			}
+
+			var jobSpan trace.Span
+			jobCtx, jobSpan = m.tracer.Start(ctx, jobSpanName, //nolint:spancheck
+				trace.WithAttributes(m.insertJobSpanAttributes(params)...),
+				trace.WithSpanKind(trace.SpanKindProducer))
+			jobSpans = append(jobSpans, jobSpan)
+		}
+
+		if periodicJobID, ok := periodicJobFromMetadata(params.Metadata); ok {
+			insert := &periodicInsert{index: i, params: params, periodicJobID: periodicJobID, span: noopSpan}
+			if sampled {
+				_, insert.span = m.tracer.Start(ctx, prefix+"periodic_job_enqueue", //nolint:spancheck
+					trace.WithAttributes(insert.attributes()...),
+					trace.WithSpanKind(trace.SpanKindProducer))
+			}
+			periodicInserts = append(periodicInserts, insert)
+		}
+
+		if m.propagator != nil {
+			params.Metadata = injectTraceContext(jobCtx, m.propagator, m.config.TracePropagationMetadataPath, params.Metadata)
 		}
 	}
 
-	insertRes, err = doInner(ctx)
+	innerCtx := ctx
+	if !sampled {
+		innerCtx = unsampledSpanContext(ctx)
+	}
+
+	insertRes, err = doInner(context.WithValue(innerCtx, hookTimingsContextKey{}, timings))
 	panicked = false
 	return insertRes, err
 }
 
+// InsertBegin is invoked by River as a hook for each job being inserted, after
+// InsertMany has started and before the jobs are inserted. It's used to add a
+// `river.insert_begin` event to the batch's span at the time it was invoked for
+// the last job, marking the end of insert hooks up to and including this one.
+func (m *Middleware) InsertBegin(ctx context.Context, params *rivertype.JobInsertParams) error {
+	if timings, ok := ctx.Value(hookTimingsContextKey{}).(*hookTimings); ok {
+		timings.insertBeginAt = time.Now()
+	}
+	return nil
+}
+
+// MetricEmit is invoked by River as a hook each time it emits a metric, like
+// the duration of fetching jobs to work. Metrics are mapped to instruments
+// generically based on their name and fields so that metrics added in new
+// versions of River are emitted without changes to the middleware. See
+// recordRiverMetric for details.
 func (m *Middleware) MetricEmit(ctx context.Context, params *rivertype.HookMetricEmitParams) {
-	if params == nil {
+	if params == nil || params.Metric == nil {
 		return
 	}
 
-	switch riverMetric := params.Metric.(type) {
-	case *rivertype.JobGetAvailableDurationMetric:
-		m.metrics.jobGetAvailableDuration.Record(ctx, m.durationInPreferredUnit(riverMetric.Duration),
-			metric.WithAttributes(attribute.String("queue", riverMetric.Queue)))
-	case *rivertype.JobGetAvailableCountMetric:
-		m.metrics.jobGetAvailableCount.Record(ctx, int64(riverMetric.Count),
-			metric.WithAttributes(attribute.String("queue", riverMetric.Queue)))
-	}
+	m.recordRiverMetric(ctx, params.Metric)
 }
 
 func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner func(context.Context) error) error {
 	spanName := prefix + "work"
-	if m.config.EnableWorkSpanJobKindSuffix {
+	var startOpts []trace.SpanStartOption
+	switch {
+	case m.config.EnableSemanticSpans:
+		spanName = "process " + job.Queue
+		startOpts = append(startOpts, trace.WithAttributes(
+			append(semanticSpanAttributes("work", "process", job.Queue),
+				attribute.String("messaging.message.id", strconv.FormatInt(job.ID, 10)))...,
+		))
+	case m.config.EnableWorkSpanJobKindSuffix:
 		spanName += "/" + job.Kind
 	}
 
-	var startOpts []trace.SpanStartOption
-	if m.config.EnableTracePropagation {
-		//nolint:contextcheck
-		if sc := extractSpanContext(job.Metadata); sc.IsValid() {
-			// We use a *link* to the span that enqueued this value, because river jobs are async by nature, so they may happen
-			// minutes, hours, or even days after they're enqueued, which can lead to really weird span contexts if a direct parent
-			// relationship is used.
-			startOpts = append(startOpts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	if m.propagator != nil {
+		extracted := extractTraceContext(m.propagator, m.config.TracePropagationMetadataPath, job.Metadata) //nolint:contextcheck
+
+		if m.config.EnableTracePropagation {
+			if sc := trace.SpanContextFromContext(extracted); sc.IsValid() {
+				if m.workSpanIsChild(job) {
+					ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+				} else {
+					// By default we use a *link* to the span that enqueued this value, because river jobs are async by nature, so they
+					// may happen minutes, hours, or even days after they're enqueued, which can lead to really weird span contexts if
+					// a direct parent relationship is used.
+					startOpts = append(startOpts, trace.WithLinks(trace.Link{SpanContext: sc}))
+				}
+			}
 		}
+
+		// Baggage is restored into the work context (unlike span context,
+		// which is only linked) so that it's visible to the worker and
+		// anything it calls into.
+		if m.config.EnableBaggagePropagation {
+			if bag := baggage.FromContext(extracted); bag.Len() > 0 {
+				ctx = baggage.ContextWithBaggage(ctx, bag)
+			}
+		}
+	}
+	startOpts = append(startOpts, trace.WithSpanKind(trace.SpanKindConsumer))
+
+	var (
+		parentCtx = ctx
+		sampled   = m.spanSampled(&SpanSamplerParams{Job: job, Operation: "work"})
+		span      = noopSpan
+	)
+	if sampled {
+		ctx, span = m.tracer.Start(ctx, spanName, startOpts...)
 	}
-	ctx, span := m.tracer.Start(ctx, spanName,
-		append(startOpts, trace.WithSpanKind(trace.SpanKindConsumer))...)
-	defer span.End()
+	defer func() { span.End() }()
 
 	attrs := []attribute.KeyValue{
 		attribute.Int("attempt", job.Attempt),
@@ -276,12 +862,55 @@ func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner fu
 	}
 	const statusIndex = 4
 
+	// Custom attributes included on both spans and metrics, and those only
+	// included on spans.
+	var customAttrs, customSpanAttrs []attribute.KeyValue
+	if m.config.AttributesFunc != nil {
+		customAttrs = m.config.AttributesFunc(job)
+	}
+	for _, attrPath := range m.config.AttributePaths {
+		if attr, ok := attrPath.attribute(job.EncodedArgs, job.Metadata); ok {
+			if attrPath.IncludeInMetrics {
+				customAttrs = append(customAttrs, attr)
+			} else {
+				customSpanAttrs = append(customSpanAttrs, attr)
+			}
+		}
+	}
+	attrs = append(attrs, customAttrs...)
+
+	m.emitLog(ctx, job, "river.job.start", log.SeverityInfo, "Job started", nil)
+
 	var (
 		begin    = time.Now()
 		err      error
 		panicked = true // set to false if program leaves normally
+		timings  = &hookTimings{}
 	)
+
+	// Time a job spent waiting between becoming available to be worked and
+	// starting work. Jobs may be worked slightly before their scheduled time
+	// because of clock drift between clients and the database, so clamp to
+	// zero. There's no equivalent in OpenTelemetry's semantic conventions, so
+	// this is emitted regardless of EnableSemanticMetrics.
+	if !job.ScheduledAt.IsZero() {
+		m.metrics.queueLatency.Record(ctx, m.durationInPreferredUnit(max(0, begin.Sub(job.ScheduledAt))),
+			m.metricAttributes(append([]attribute.KeyValue{
+				attribute.String("kind", job.Kind),
+				attribute.String("queue", job.Queue),
+			}, customAttrs...)...))
+	}
 	defer func() {
+		// Recover a panic so its value can be recorded, then panic again
+		// below so it's still handled by River's executor. Frames from the
+		// original panic are still on the stack at this point, so they're
+		// included in the recorded stack trace. Recovered is nil if the
+		// goroutine is exiting through runtime.Goexit instead of a panic.
+		var recovered any
+		if panicked {
+			recovered = recover()
+		}
+
 		duration := m.durationInPreferredUnit(time.Since(begin))
 
 		var (
@@ -305,6 +934,21 @@ func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner fu
 			}
 		}
 
+		outcome := workOutcome(job, panicked, err, cancelErr, snoozeErr)
+		attrs = append(attrs, attribute.String("outcome", outcome))
+
+		// Failed attempts always get a span, even if not sampled. Snoozes
+		// aren't failures.
+		if !sampled && (panicked || err != nil && snoozeErr == nil) {
+			_, span = m.tracer.Start(parentCtx, spanName, append(startOpts, trace.WithTimestamp(begin))...)
+		}
+
+		m.setWorkHookTimings(span, begin, timings)
+
+		if recovered != nil {
+			recordPanic(span, recovered)
+		}
+
 		setStatus(attrs, statusIndex, span, panicked, err)
 
 		// Add some higher cardinality attributes to spans, but keep them
@@ -316,16 +960,28 @@ func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner fu
 			attribute.String("scheduled_at", job.ScheduledAt.Format(time.RFC3339)),
 		)
 		span.SetAttributes(attrs...) // set after finalizing status
+		span.SetAttributes(customSpanAttrs...)
 		if snoozeErr != nil {
 			span.SetAttributes(attribute.String("snooze.duration", snoozeErr.Duration.String()))
 		}
 
+		m.emitWorkEndLog(ctx, job, outcome, err, recovered, snoozeErr)
+
 		// This allocates a new slice, so make sure to do it as few times as possible.
-		measurementOpt := metric.WithAttributes(attrs...)
+		measurementOpt := m.metricAttributes(attrs...)
 
 		m.metrics.workCount.Add(ctx, 1, measurementOpt)
-		m.metrics.workDuration.Record(ctx, duration, measurementOpt)
-		m.metrics.workDurationHistogram.Record(ctx, duration, measurementOpt)
+		if m.metrics.workDuration != nil {
+			m.metrics.workDuration.Record(ctx, duration, measurementOpt)
+		}
+		if m.metrics.workDurationHistogram != nil {
+			m.metrics.workDurationHistogram.Record(ctx, duration, measurementOpt)
+		}
+
+		// A panic on a job's last attempt discards it too.
+		if outcome == "discarded" || outcome == "panicked" && jobOnFinalAttempt(job) {
+			m.metrics.jobDiscardedCount.Add(ctx, 1, measurementOpt)
+		}
 
 		if m.config.EnableSemanticMetrics {
 			measurementOpt := metric.WithAttributes(
@@ -337,9 +993,18 @@ func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner fu
 			m.metrics.messagingClientOperationDuration.Record(ctx, duration, measurementOpt)
 			m.metrics.messagingProcessDuration.Record(ctx, duration, measurementOpt)
 		}
+
+		if recovered != nil {
+			panic(recovered)
+		}
 	}()
 
-	err = doInner(ctx)
+	innerCtx := ctx
+	if !sampled {
+		innerCtx = unsampledSpanContext(ctx)
+	}
+
+	err = doInner(context.WithValue(innerCtx, hookTimingsContextKey{}, timings))
 	panicked = false
 	return err
 }
@@ -355,6 +1020,254 @@ func (m *Middleware) durationInPreferredUnit(duration time.Duration) float64 {
 	}
 }
 
+// Emits a log record for a job lifecycle event if a LoggerProvider was
+// configured. ctx should contain the job's work span so the record is
+// correlated with it.
+func (m *Middleware) emitLog(ctx context.Context, job *rivertype.JobRow, eventName string, severity log.Severity, body string, err error, attrs ...log.KeyValue) {
+	if m.logger == nil || !m.logger.Enabled(ctx, log.EnabledParameters{EventName: eventName, Severity: severity}) {
+		return
+	}
+
+	var record log.Record
+	record.SetBody(log.StringValue(body))
+	record.SetErr(err)
+	record.SetEventName(eventName)
+	record.SetSeverity(severity)
+	record.SetTimestamp(time.Now())
+	record.AddAttributes(
+		log.Int("attempt", job.Attempt),
+		log.Int64("id", job.ID),
+		log.String("kind", job.Kind),
+		log.String("queue", job.Queue),
+	)
+	record.AddAttributes(attrs...)
+
+	m.logger.Emit(ctx, record)
+}
+
+// Emits a log record for the outcome of a job that didn't complete
+// successfully. Panics are logged as failures, or as a discard if they
+// happened on the job's last attempt. recovered is a value recovered from a
+// panic, if there was one.
+func (m *Middleware) emitWorkEndLog(ctx context.Context, job *rivertype.JobRow, outcome string, err error, recovered any, snoozeErr *river.JobSnoozeError) {
+	if recovered != nil {
+		err = fmt.Errorf("panic: %v", recovered)
+	}
+
+	switch outcome {
+	case "cancelled":
+		m.emitLog(ctx, job, "river.job.cancel", log.SeverityWarn, "Job cancelled", err)
+	case "discarded":
+		m.emitLog(ctx, job, "river.job.discard", log.SeverityError, "Job discarded after exhausting attempts", err)
+	case "panicked":
+		if jobOnFinalAttempt(job) {
+			m.emitLog(ctx, job, "river.job.discard", log.SeverityError, "Job discarded after exhausting attempts", err)
+		} else {
+			m.emitLog(ctx, job, "river.job.failure", log.SeverityWarn, "Job failed and will be retried", err)
+		}
+	case "retryable":
+		m.emitLog(ctx, job, "river.job.failure", log.SeverityWarn, "Job failed and will be retried", err)
+	case "snoozed":
+		m.emitLog(ctx, job, "river.job.snooze", log.SeverityInfo, "Job snoozed", nil,
+			log.String("snooze.duration", snoozeErr.Duration.String()))
+	}
+}
+
+// Attributes for a per-job insert span that are known before insertion.
+func (m *Middleware) insertJobSpanAttributes(params *rivertype.JobInsertParams) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("kind", params.Kind),
+		attribute.Int("priority", params.Priority),
+		attribute.String("queue", params.Queue),
+	}
+	if params.ScheduledAt != nil {
+		attrs = append(attrs, attribute.String("scheduled_at", params.ScheduledAt.Format(time.RFC3339)))
+	}
+	if m.config.EnableSemanticSpans {
+		attrs = append(attrs, semanticSpanAttributes("insert", "create", params.Queue)...)
+	}
+	if m.config.InsertAttributesFunc != nil {
+		attrs = append(attrs, m.config.InsertAttributesFunc(params)...)
+	}
+	for _, attrPath := range m.config.AttributePaths {
+		if attr, ok := attrPath.attribute(params.EncodedArgs, params.Metadata); ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// metricAttributes produces a measurement option for the given attributes after
+// applying MetricAttributeFilter. This allocates a new slice, so make sure to do
+// it as few times as possible.
+func (m *Middleware) metricAttributes(attrs ...attribute.KeyValue) metric.MeasurementOption {
+	if m.config.MetricAttributeFilter == nil {
+		return metric.WithAttributes(attrs...)
+	}
+
+	filtered := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		if m.config.MetricAttributeFilter(attr) {
+			filtered = append(filtered, attr)
+		}
+	}
+	return metric.WithAttributeSet(attribute.NewSet(filtered...)) // NewSet takes ownership of filtered rather than copying it
+}
+
+// insertManyMaxJobIDs returns the maximum number of job IDs to record on
+// insert_many spans, with 0 meaning none.
+func (m *Middleware) insertManyMaxJobIDs() int {
+	switch {
+	case m.config.InsertManyMaxJobIDs < 0:
+		return 0
+	case m.config.InsertManyMaxJobIDs == 0:
+		return insertManyMaxJobIDsDefault
+	default:
+		return m.config.InsertManyMaxJobIDs
+	}
+}
+
+// WorkBegin is invoked by River as a hook after Work has started and just
+// before a job is worked. It's used to add a `river.work_begin` event to the
+// job's work span. River decodes job args right after WorkBegin hooks run, so
+// if the middleware is the last hook, the event marks both the end of hooks
+// and the start of args decoding.
+func (m *Middleware) WorkBegin(ctx context.Context, job *rivertype.JobRow) error {
+	if timings, ok := ctx.Value(hookTimingsContextKey{}).(*hookTimings); ok {
+		timings.workBeginAt = time.Now()
+	}
+	return nil
+}
+
+// WorkEnd is invoked by River as a hook just after a job is worked. It's used
+// to add a `river.work_end` event to the job's work span. err is returned
+// unchanged.
+func (m *Middleware) WorkEnd(ctx context.Context, job *rivertype.JobRow, err error) error {
+	if timings, ok := ctx.Value(hookTimingsContextKey{}).(*hookTimings); ok {
+		timings.workEndAt = time.Now()
+	}
+	return err
+}
+
+// Adds events for timings recorded by WorkBegin and WorkEnd to a work span,
+// along with attributes breaking down where time in the span went:
+//
+//   - `duration.before_work`: Time from the start of the span to WorkBegin,
+//     spent in inner middleware and hooks that ran before this one.
+//   - `duration.work`: Time from WorkBegin to WorkEnd, spent in hooks that
+//     ran after this one, decoding args, and in the worker itself.
+//   - `duration.after_work`: Time from WorkEnd to the end of the span, spent
+//     in hooks that ran after this one and unwinding inner middleware.
+//
+// Durations are in DurationUnit.
+func (m *Middleware) setWorkHookTimings(span trace.Span, begin time.Time, timings *hookTimings) {
+	if timings.workBeginAt.IsZero() {
+		return
+	}
+
+	span.AddEvent(prefix+"work_begin", trace.WithTimestamp(timings.workBeginAt))
+	span.SetAttributes(attribute.Float64("duration.before_work", m.durationInPreferredUnit(timings.workBeginAt.Sub(begin))))
+
+	// WorkEnd isn't invoked if the worker panicked.
+	if timings.workEndAt.IsZero() {
+		return
+	}
+
+	span.AddEvent(prefix+"work_end", trace.WithTimestamp(timings.workEndAt))
+	span.SetAttributes(
+		attribute.Float64("duration.after_work", m.durationInPreferredUnit(time.Since(timings.workEndAt))),
+		attribute.Float64("duration.work", m.durationInPreferredUnit(timings.workEndAt.Sub(timings.workBeginAt))),
+	)
+}
+
+// Ends spans and records metrics for periodic jobs in an inserted batch. A
+// periodic job's delay is the time between when it was scheduled to run, which
+// River sets as its scheduled at time, and when it was enqueued. Runs that are
+// late, like because no leader was elected to run the periodic job enqueuer,
+// show up as a long delay. River doesn't expose periodic job schedules, so
+// those aren't recorded.
+func (m *Middleware) finishPeriodicInserts(ctx context.Context, inserts []*periodicInsert, insertRes []*rivertype.JobInsertResult, begin time.Time, panicked bool, err error) {
+	for _, insert := range inserts {
+		attrs := append([]attribute.KeyValue{
+			attribute.String("status", ""), // replaced below
+		}, insert.attributes()...)
+		const statusIndex = 0
+
+		setStatus(attrs, statusIndex, insert.span, panicked, err)
+		insert.span.SetAttributes(attrs...)
+
+		// Results are returned in the same order as params.
+		if insert.index < len(insertRes) && insertRes[insert.index] != nil && insertRes[insert.index].Job != nil {
+			insert.span.SetAttributes(attribute.Int64("id", insertRes[insert.index].Job.ID))
+		}
+
+		measurementOpt := m.metricAttributes(attrs...)
+
+		m.metrics.periodicJobEnqueueCount.Add(ctx, 1, measurementOpt)
+
+		if scheduledAt := insert.params.ScheduledAt; scheduledAt != nil {
+			// Jobs may be enqueued slightly ahead of schedule, so clamp to zero.
+			delay := m.durationInPreferredUnit(max(0, begin.Sub(*scheduledAt)))
+
+			insert.span.SetAttributes(
+				attribute.Float64("enqueue_delay", delay),
+				attribute.String("scheduled_at", scheduledAt.Format(time.RFC3339)),
+			)
+			m.metrics.periodicJobEnqueueDelay.Record(ctx, delay, measurementOpt)
+		}
+
+		insert.span.End()
+	}
+}
+
+// Whether spans should be recorded for an operation according to SpanSampler.
+func (m *Middleware) spanSampled(params *SpanSamplerParams) bool {
+	if m.config.SpanSampler == nil {
+		return true
+	}
+	return m.config.SpanSampler(params)
+}
+
+// unsampledSpanContext returns a context containing a span context marked as
+// not sampled, standing in for the span of an operation that SpanSampler
+// decided not to sample. Parent-based samplers drop spans started from it, so
+// spans started by a worker or during an insert are dropped along with the
+// operation's instead of becoming roots of their own traces or attaching to a
+// remote parent. The trace of a span already in ctx is kept.
+func unsampledSpanContext(ctx context.Context) context.Context {
+	parent := trace.SpanContextFromContext(ctx)
+
+	traceID := parent.TraceID()
+	if !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+
+	var spanID trace.SpanID
+	_, _ = rand.Read(spanID[:])
+
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		SpanID:     spanID,
+		TraceID:    traceID,
+		TraceState: parent.TraceState(),
+	}))
+}
+
+// workSpanIsChild returns true if the work span for the given job should be
+// started as a child of the span that enqueued it rather than linked to it,
+// according to the configured TracePropagationMode.
+func (m *Middleware) workSpanIsChild(job *rivertype.JobRow) bool {
+	switch m.config.TracePropagationMode {
+	case "parent":
+		return true
+	case "parent_if_recent":
+		return job.Attempt <= 1 && time.Since(job.CreatedAt) <= m.config.TracePropagationParentMaxDelay
+	case "link":
+		fallthrough
+	default:
+		return false
+	}
+}
+
 func mustFloat64Gauge(meter metric.Meter, name string, options ...metric.Float64GaugeOption) metric.Float64Gauge {
 	metric, err := meter.Float64Gauge(name, options...)
 	if err != nil {
@@ -363,7 +1276,11 @@ func mustFloat64Gauge(meter metric.Meter, name string, options ...metric.Float64
 	return metric
 }
 
-func mustFloat64Histogram(meter metric.Meter, name string, options ...metric.Float64HistogramOption) metric.Float64Histogram {
+func mustFloat64Histogram(meter metric.Meter, bucketBoundaries map[string][]float64, name string, options ...metric.Float64HistogramOption) metric.Float64Histogram {
+	if boundaries, ok := bucketBoundaries[name]; ok {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+
 	metric, err := meter.Float64Histogram(name, options...)
 	if err != nil {
 		panic(err)
@@ -371,7 +1288,11 @@ func mustFloat64Histogram(meter metric.Meter, name string, options ...metric.Flo
 	return metric
 }
 
-func mustInt64Histogram(meter metric.Meter, name string, options ...metric.Int64HistogramOption) metric.Int64Histogram {
+func mustInt64Histogram(meter metric.Meter, bucketBoundaries map[string][]float64, name string, options ...metric.Int64HistogramOption) metric.Int64Histogram {
+	if boundaries, ok := bucketBoundaries[name]; ok {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+
 	metric, err := meter.Int64Histogram(name, options...)
 	if err != nil {
 		panic(err)
@@ -387,12 +1308,64 @@ func mustInt64Counter(meter metric.Meter, name string, options ...metric.Int64Co
 	return metric
 }
 
-// injectTraceContext injects the current span context from ctx into metadata
-// JSON under the W3C "traceparent" (and optionally "tracestate") key. If
+// A propagator that leaves the given carrier fields out of what the propagator
+// it wraps injects and extracts.
+type fieldOmittingPropagator struct {
+	propagation.TextMapPropagator
+
+	omittedFields []string
+}
+
+func (p *fieldOmittingPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	injected := make(propagation.MapCarrier)
+	p.TextMapPropagator.Inject(ctx, injected)
+	for k, v := range injected {
+		if !slices.Contains(p.omittedFields, strings.ToLower(k)) {
+			carrier.Set(k, v)
+		}
+	}
+}
+
+func (p *fieldOmittingPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return p.TextMapPropagator.Extract(ctx, &fieldOmittingCarrier{omittedFields: p.omittedFields, TextMapCarrier: carrier})
+}
+
+func (p *fieldOmittingPropagator) Fields() []string {
+	return slices.DeleteFunc(slices.Clone(p.TextMapPropagator.Fields()), func(field string) bool {
+		return slices.Contains(p.omittedFields, strings.ToLower(field))
+	})
+}
+
+// A carrier that hides the given fields from a propagator extracting from it.
+type fieldOmittingCarrier struct {
+	propagation.TextMapCarrier
+
+	omittedFields []string
+}
+
+func (c *fieldOmittingCarrier) Get(key string) string {
+	if slices.Contains(c.omittedFields, strings.ToLower(key)) {
+		return ""
+	}
+	return c.TextMapCarrier.Get(key)
+}
+
+func (c *fieldOmittingCarrier) Keys() []string {
+	return slices.DeleteFunc(c.TextMapCarrier.Keys(), func(key string) bool {
+		return slices.Contains(c.omittedFields, strings.ToLower(key))
+	})
+}
+
+// injectTraceContext injects propagated context from ctx into metadata JSON
+// using the given propagator. For W3C trace context this is the "traceparent"
+// (and optionally "tracestate") key, and for W3C baggage the "baggage" key. If
 // injection fails for any reason the original metadata is returned unchanged.
-func injectTraceContext(ctx context.Context, metadata []byte) []byte {
+//
+// Keys are stored at the top level of metadata unless metadataPath is
+// non-empty, in which case they're stored in an object at that path instead.
+func injectTraceContext(ctx context.Context, propagator propagation.TextMapPropagator, metadataPath string, metadata []byte) []byte {
 	carrier := make(propagation.MapCarrier)
-	propagation.TraceContext{}.Inject(ctx, carrier)
+	propagator.Inject(ctx, carrier)
 	if len(carrier) == 0 {
 		return metadata
 	}
@@ -401,6 +1374,10 @@ func injectTraceContext(ctx context.Context, metadata []byte) []byte {
 	}
 	original := metadata
 	for k, v := range carrier {
+		if metadataPath != "" {
+			k = metadataPath + "." + k
+		}
+
 		var err error
 		metadata, err = sjson.SetBytes(metadata, k, v)
 		if err != nil {
@@ -410,16 +1387,20 @@ func injectTraceContext(ctx context.Context, metadata []byte) []byte {
 	return metadata
 }
 
-// extractSpanContext reads W3C trace context from metadata JSON and returns the
-// remote SpanContext it encodes. Returns a zero SpanContext (IsValid() == false)
-// if no traceparent is present or the metadata cannot be parsed.
-func extractSpanContext(metadata []byte) trace.SpanContext {
+// extractTraceContext reads propagated context from metadata JSON using the
+// given propagator and returns a new context containing the remote
+// SpanContext and baggage it encodes (if any). Returns an empty context if
+// nothing is present or the metadata cannot be parsed.
+//
+// Keys are read from the top level of metadata, and then from an object at
+// metadataPath if it's non-empty, with the latter taking precedence.
+func extractTraceContext(propagator propagation.TextMapPropagator, metadataPath string, metadata []byte) context.Context {
 	if len(metadata) == 0 {
-		return trace.SpanContext{}
+		return context.Background()
 	}
 	var meta map[string]any
 	if err := json.Unmarshal(metadata, &meta); err != nil {
-		return trace.SpanContext{}
+		return context.Background()
 	}
 	carrier := make(propagation.MapCarrier)
 	for k, v := range meta {
@@ -427,12 +1408,85 @@ func extractSpanContext(metadata []byte) trace.SpanContext {
 			carrier[k] = s
 		}
 	}
+	if metadataPath != "" {
+		gjson.GetBytes(metadata, metadataPath).ForEach(func(k, v gjson.Result) bool {
+			if v.Type == gjson.String {
+				carrier[k.String()] = v.String()
+			}
+			return true
+		})
+	}
 	// We use context.Background here because the only purpose of this function is to return
-	// a span context for *linking*. If one doesn't exist, we don't want to extract anything -
-	// and we certainly don't want to extract the span from `ctx`, which would most often lead to us
-	// linking to ourselves, which is pretty obviously incorrect!
-	extracted := propagation.TraceContext{}.Extract(context.Background(), carrier)
-	return trace.SpanFromContext(extracted).SpanContext()
+	// a span context for *linking* and baggage for restoring. If one doesn't exist, we don't want to
+	// extract anything - and we certainly don't want to extract the span from `ctx`, which would most
+	// often lead to us linking to ourselves, which is pretty obviously incorrect!
+	return propagator.Extract(context.Background(), carrier)
+}
+
+// Whether a job is being worked on its last attempt, meaning that it'll be
+// discarded if it fails.
+func jobOnFinalAttempt(job *rivertype.JobRow) bool {
+	return job.Attempt >= job.MaxAttempts
+}
+
+// Gets whether a job was inserted by River's periodic job enqueuer from its
+// metadata, along with its periodic job ID if it was configured with one.
+func periodicJobFromMetadata(metadata []byte) (string, bool) {
+	if !gjson.GetBytes(metadata, "periodic").Bool() {
+		return "", false
+	}
+	return gjson.GetBytes(metadata, "river:periodic_job_id").String(), true
+}
+
+// Classifies the outcome of working a job by what'll happen to it next, which
+// unlike status distinguishes an error that'll be retried from one on a job's
+// last attempt that'll cause it to be discarded. One of completed, retryable,
+// discarded, cancelled, snoozed, or panicked.
+func workOutcome(job *rivertype.JobRow, panicked bool, err error, cancelErr *river.JobCancelError, snoozeErr *river.JobSnoozeError) string {
+	switch {
+	case panicked:
+		return "panicked"
+	case err == nil:
+		return "completed"
+	case cancelErr != nil:
+		return "cancelled"
+	case snoozeErr != nil:
+		return "snoozed"
+	case jobOnFinalAttempt(job):
+		return "discarded"
+	default:
+		return "retryable"
+	}
+}
+
+// Records a value recovered from a panic as an exception event on the given
+// span according to OpenTelemetry's semantic conventions for exceptions.
+func recordPanic(span trace.Span, recovered any) {
+	message := fmt.Sprint(recovered)
+	if err, ok := recovered.(error); ok {
+		message = err.Error()
+	}
+
+	span.AddEvent("exception", trace.WithAttributes(
+		attribute.String("exception.message", message),
+		attribute.String("exception.stacktrace", string(debug.Stack())),
+		attribute.String("exception.type", fmt.Sprintf("%T", recovered)),
+	))
+}
+
+// Produces attributes for a span following OpenTelemetry's semantic conventions
+// for messaging. queue may be empty if an operation doesn't have a single
+// destination.
+func semanticSpanAttributes(operationName, operationType, queue string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.operation.name", operationName),
+		attribute.String("messaging.operation.type", operationType),
+		attribute.String("messaging.system", "river"),
+	}
+	if queue != "" {
+		attrs = append(attrs, attribute.String("messaging.destination.name", queue))
+	}
+	return attrs
 }
 
 // Sets success status on the given span and within the set of attributes. The
@@ -456,6 +1510,7 @@ func setStatus(attrs []attribute.KeyValue, statusIndex int, span trace.Span, pan
 		span.SetStatus(codes.Ok, "")
 	case err != nil:
 		attrs[statusIndex] = attribute.String("status", "error")
+		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 	default:
 		attrs[statusIndex] = attribute.String("status", "ok")
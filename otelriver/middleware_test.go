@@ -5,18 +5,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/riverqueue/river/rivertype"
 )
@@ -24,7 +32,10 @@ import (
 // Verify interface compliance.
 var (
 	_ rivertype.JobInsertMiddleware = &Middleware{}
+	_ rivertype.HookInsertBegin     = &Middleware{}
 	_ rivertype.HookMetricEmit      = &Middleware{}
+	_ rivertype.HookWorkBegin       = &Middleware{}
+	_ rivertype.HookWorkEnd         = &Middleware{}
 	_ rivertype.WorkerMiddleware    = &Middleware{}
 )
 
@@ -34,24 +45,31 @@ func TestMiddleware(t *testing.T) {
 	ctx := context.Background()
 
 	type testBundle struct {
-		metricReader  *metric.ManualReader
-		traceExporter *tracetest.InMemoryExporter
+		logProcessor   *testLogProcessor
+		metricReader   *metric.ManualReader
+		traceExporter  *tracetest.InMemoryExporter
+		tracerProvider *sdktrace.TracerProvider
 	}
 
 	setupConfig := func(t *testing.T, config *MiddlewareConfig) (*Middleware, *testBundle) {
 		t.Helper()
 
 		var (
-			metricReader  = metric.NewManualReader()
-			traceExporter = tracetest.NewInMemoryExporter()
+			logProcessor   = &testLogProcessor{}
+			metricReader   = metric.NewManualReader()
+			traceExporter  = tracetest.NewInMemoryExporter()
+			tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(traceExporter))
 		)
 
+		config.LoggerProvider = sdklog.NewLoggerProvider(sdklog.WithProcessor(logProcessor))
 		config.MeterProvider = metric.NewMeterProvider(metric.WithReader(metricReader))
-		config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(traceExporter))
+		config.TracerProvider = tracerProvider
 
 		return NewMiddleware(config), &testBundle{
-			metricReader:  metricReader,
-			traceExporter: traceExporter,
+			logProcessor:   logProcessor,
+			metricReader:   metricReader,
+			traceExporter:  traceExporter,
+			tracerProvider: tracerProvider,
 		}
 	}
 
@@ -123,6 +141,111 @@ func TestMiddleware(t *testing.T) {
 		require.EqualValues(t, 42, metricData.DataPoints[0].Sum)
 	})
 
+	t.Run("MetricUnknownType", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+			Metric: &testRiverMetric{
+				Count:    3,
+				Duration: 1500 * time.Millisecond,
+				Leader:   true,
+				Queue:    "critical",
+			},
+		})
+
+		attrs := []attribute.KeyValue{attribute.Bool("leader", true), attribute.String("queue", "critical")}
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		{
+			metric, metricData := requireInt64HistogramCount(t, metrics, "river.test_metric_count", 1, attrs...)
+			require.Equal(t, "Metric emitted by River as test_metric_count", metric.Description)
+			require.Empty(t, metric.Unit)
+			require.EqualValues(t, 3, metricData.DataPoints[0].Sum)
+		}
+		{
+			metric, metricData := requireHistogramCount(t, metrics, "river.test_metric_duration", 1, attrs...)
+			require.Equal(t, "s", metric.Unit)
+			require.InDelta(t, 1.5, metricData.DataPoints[0].Sum, 0.001)
+		}
+		requireNoMetric(t, metrics, "river.metric_emit_unmapped_count")
+	})
+
+	t.Run("MetricBucketBoundaries", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			HistogramBucketBoundaries: map[string][]float64{"river.job_get_available_count": {1, 10, 100}},
+		})
+
+		middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+			Metric: &rivertype.JobGetAvailableCountMetric{Count: 42, Queue: "critical"},
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		_, metricData := requireInt64HistogramCount(t, metrics, "river.job_get_available_count", 1)
+		require.Equal(t, []float64{1, 10, 100}, metricData.DataPoints[0].Bounds)
+	})
+
+	t.Run("MetricBucketBoundariesUnknownMetric", func(t *testing.T) {
+		t.Parallel()
+
+		// Keys for metrics the middleware doesn't know about up front are
+		// accepted since any could be emitted by River.
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			HistogramBucketBoundaries: map[string][]float64{"river.test_metric_count": {1, 10, 100}},
+		})
+
+		middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+			Metric: &testRiverMetric{Count: 42, Duration: time.Second, Queue: "critical"},
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		_, metricData := requireInt64HistogramCount(t, metrics, "river.test_metric_count", 1)
+		require.Equal(t, []float64{1, 10, 100}, metricData.DataPoints[0].Bounds)
+	})
+
+	t.Run("MetricInvalidInstrumentName", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		// The SDK errors on the invalid name, but the middleware doesn't panic
+		// and still records to the instrument it returned.
+		require.NotPanics(t, func() {
+			middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+				Metric: &testRiverMetricInvalidName{Count: 42},
+			})
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireInt64HistogramCount(t, metrics, "river.invalid metric name!", 1)
+	})
+
+	t.Run("MetricUnmapped", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+			Metric: &testRiverMetricNoValues{Queue: "critical"},
+		})
+		middleware.MetricEmit(ctx, &rivertype.HookMetricEmitParams{
+			Metric: &testRiverMetricNoValues{Queue: "critical"},
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.metric_emit_unmapped_count", 2,
+			attribute.String("metric_name", "test_metric_no_values"))
+		requireNoMetric(t, metrics, "river.test_metric_no_values")
+	})
+
 	t.Run("InsertManySuccess", func(t *testing.T) {
 		t.Parallel()
 
@@ -149,6 +272,7 @@ func TestMiddleware(t *testing.T) {
 		require.Equal(t, codes.Ok, span.Status.Code)
 		require.Equal(t, []string{"no_op"}, getAttribute(t, span.Attributes, "kinds").AsStringSlice())
 		require.EqualValues(t, 0, getAttribute(t, span.Attributes, "unique_skipped_as_duplicate_count").AsInt64())
+		require.Equal(t, []int64{123}, getAttribute(t, span.Attributes, "ids").AsInt64Slice())
 
 		var (
 			expectedAttrs = []attribute.KeyValue{
@@ -369,6 +493,47 @@ func TestMiddleware(t *testing.T) {
 			getAttribute(t, spans[0].Attributes, "kinds").AsStringSlice())
 	})
 
+	t.Run("InsertManyMaxJobIDs", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{InsertManyMaxJobIDs: 2})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 1}},
+				{Job: &rivertype.JobRow{ID: 2}},
+				{Job: &rivertype.JobRow{ID: 3}},
+			}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{}, {}, {}}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, []int64{1, 2}, getAttribute(t, spans[0].Attributes, "ids").AsInt64Slice())
+		require.True(t, getAttribute(t, spans[0].Attributes, "ids_truncated").AsBool())
+	})
+
+	t.Run("InsertManyMaxJobIDsDisabled", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{InsertManyMaxJobIDs: -1})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{}}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		for _, attr := range spans[0].Attributes {
+			require.NotEqual(t, attribute.Key("ids"), attr.Key)
+		}
+	})
+
 	t.Run("InsertManyInjectsTraceparent", func(t *testing.T) {
 		t.Parallel()
 
@@ -397,163 +562,801 @@ func TestMiddleware(t *testing.T) {
 		require.Contains(t, traceparent, insertSpan.SpanContext.SpanID().String())
 	})
 
-	t.Run("InsertManyDurationUnitMS", func(t *testing.T) {
+	t.Run("InsertManyInjectsBaggage", func(t *testing.T) {
 		t.Parallel()
 
-		middleware, bundle := setupConfig(t, &MiddlewareConfig{
-			DurationUnit: "ms",
-		})
+		middleware, _ := setupConfig(t, &MiddlewareConfig{EnableBaggagePropagation: true})
+
+		member, err := baggage.NewMember("tenant_id", "tenant_123")
+		require.NoError(t, err)
+		bag, err := baggage.New(member)
+		require.NoError(t, err)
 
+		params := []*rivertype.JobInsertParams{{Kind: "no_op"}}
 		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
-			return []*rivertype.JobInsertResult{
-				{Job: &rivertype.JobRow{ID: 123}},
-			}, nil
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
 		}
 
-		insertRes, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, doInner)
+		_, err = middleware.InsertMany(baggage.ContextWithBaggage(ctx, bag), params, doInner)
 		require.NoError(t, err)
-		require.Equal(t, []*rivertype.JobInsertResult{
-			{Job: &rivertype.JobRow{ID: 123}},
-		}, insertRes)
 
-		var metrics metricdata.ResourceMetrics
-		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
-		requireSum(t, metrics, "river.insert_count", 1)
-		requireSum(t, metrics, "river.insert_many_count", 1)
-		{
-			metric, _ := requireGaugeNotEmpty(t, metrics, "river.insert_many_duration")
-			require.Equal(t, "ms", metric.Unit)
-		}
-		{
-			metric, _ := requireHistogramCount(t, metrics, "river.insert_many_duration_histogram", 1)
-			require.Equal(t, "ms", metric.Unit)
-		}
+		var meta map[string]any
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Equal(t, "tenant_id=tenant_123", meta["baggage"])
+
+		// Trace propagation wasn't enabled, so no traceparent is expected.
+		require.NotContains(t, meta, "traceparent")
 	})
 
-	t.Run("InsertManyEnableSemanticMetrics", func(t *testing.T) {
+	t.Run("InsertManyInjectsTraceparentMetadataPath", func(t *testing.T) {
 		t.Parallel()
 
 		middleware, bundle := setupConfig(t, &MiddlewareConfig{
-			EnableSemanticMetrics: true,
+			EnableTracePropagation:       true,
+			TracePropagationMetadataPath: "telemetry.otel",
 		})
 
+		params := []*rivertype.JobInsertParams{{Kind: "no_op", Metadata: []byte(`{"traceparent":"mine"}`)}}
 		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
-			return []*rivertype.JobInsertResult{
-				{Job: &rivertype.JobRow{ID: 123}},
-			}, nil
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
 		}
 
-		insertRes, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, doInner)
+		_, err := middleware.InsertMany(ctx, params, doInner)
 		require.NoError(t, err)
-		require.Equal(t, []*rivertype.JobInsertResult{
-			{Job: &rivertype.JobRow{ID: 123}},
-		}, insertRes)
 
-		var (
-			expectedAttrs = []attribute.KeyValue{
-				attribute.String("messaging.operation.name", "insert_many"),
-				attribute.String("messaging.system", "river"),
-			}
-			metrics metricdata.ResourceMetrics
-		)
-		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
-		requireSum(t, metrics, "messaging.client.sent.messages", 1, expectedAttrs...)
-		{
-			metric, _ := requireHistogramCount(t, metrics, "messaging.client.operation.duration", 1, expectedAttrs...)
-			require.Equal(t, "s", metric.Unit)
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		insertSpan := spans[0]
+
+		var meta struct {
+			Telemetry struct {
+				Otel map[string]string `json:"otel"`
+			} `json:"telemetry"`
+			Traceparent string `json:"traceparent"`
 		}
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Contains(t, meta.Telemetry.Otel["traceparent"], insertSpan.SpanContext.SpanID().String())
+
+		// Existing top-level key is left untouched.
+		require.Equal(t, "mine", meta.Traceparent)
 	})
 
-	t.Run("WorkSuccess", func(t *testing.T) {
+	t.Run("InsertManyInjectsWithPropagator", func(t *testing.T) {
 		t.Parallel()
 
-		middleware, bundle := setup(t)
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			Propagator:             &testPropagator{},
+		})
 
-		doInner := func(ctx context.Context) error {
-			return nil
+		params := []*rivertype.JobInsertParams{{Kind: "no_op"}}
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
 		}
 
-		var (
-			createdAt   = time.Now()
-			scheduledAt = time.Now().Add(1 * time.Second)
-		)
-		err := middleware.Work(ctx, &rivertype.JobRow{
-			ID:          123,
-			Attempt:     6,
-			CreatedAt:   createdAt,
-			Kind:        "no_op",
-			Priority:    1,
-			Queue:       "my_queue",
-			ScheduledAt: scheduledAt,
-			Tags:        []string{"a", "b"},
-		}, doInner)
+		_, err := middleware.InsertMany(ctx, params, doInner)
 		require.NoError(t, err)
 
 		spans := bundle.traceExporter.GetSpans()
 		require.Len(t, spans, 1)
+		insertSpan := spans[0]
 
-		span := spans[0]
-		require.Equal(t, int64(123), getAttribute(t, span.Attributes, "id").AsInt64())
-		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
-		require.Equal(t, createdAt.Format(time.RFC3339), getAttribute(t, span.Attributes, "created_at").AsString())
-		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "queue").AsString())
-		require.Equal(t, int64(1), getAttribute(t, span.Attributes, "priority").AsInt64())
-		require.Equal(t, "ok", getAttribute(t, span.Attributes, "status").AsString())
-		require.Equal(t, scheduledAt.Format(time.RFC3339), getAttribute(t, span.Attributes, "scheduled_at").AsString())
-		require.Equal(t, []string{"a", "b"}, getAttribute(t, span.Attributes, "tag").AsStringSlice())
-		require.Equal(t, "river.work", span.Name)
-		require.Equal(t, codes.Ok, span.Status.Code)
+		// Both the custom format and W3C trace context are injected.
+		var meta map[string]any
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Equal(t, insertSpan.SpanContext.TraceID().String()+"-"+insertSpan.SpanContext.SpanID().String(), meta[testPropagatorKey])
+		require.Contains(t, meta, "traceparent")
+	})
 
-		var (
-			expectedAttrs = []attribute.KeyValue{
-				attribute.String("status", "ok"),
-			}
-			metrics metricdata.ResourceMetrics
-		)
-		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
-		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
-		{
-			metric, _ := requireGaugeNotEmpty(t, metrics, "river.work_duration", expectedAttrs...)
-			require.Equal(t, "s", metric.Unit)
-		}
-		{
-			metric, _ := requireHistogramCount(t, metrics, "river.work_duration_histogram", 1, expectedAttrs...)
-			require.Equal(t, "s", metric.Unit)
+	t.Run("InsertManyCompositePropagatorTraceOnly", func(t *testing.T) {
+		t.Parallel()
+
+		// Like a typical global propagator, includes both W3C formats.
+		middleware, _ := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			Propagator:             propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		})
+
+		member, err := baggage.NewMember("tenant_id", "tenant_123")
+		require.NoError(t, err)
+		bag, err := baggage.New(member)
+		require.NoError(t, err)
+
+		params := []*rivertype.JobInsertParams{{Kind: "no_op"}}
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
 		}
 
-		// Requires EnableSemanticMetrics.
-		requireNoMetric(t, metrics, "messaging.client.consumed.messages")
-		requireNoMetric(t, metrics, "messaging.client.operation.duration")
-		requireNoMetric(t, metrics, "messaging.process.duration")
+		_, err = middleware.InsertMany(baggage.ContextWithBaggage(ctx, bag), params, doInner)
+		require.NoError(t, err)
+
+		var meta map[string]any
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Contains(t, meta, "traceparent")
+
+		// Baggage propagation wasn't enabled, so baggage is left out even
+		// though the propagator includes it.
+		require.NotContains(t, meta, "baggage")
 	})
 
-	t.Run("WorkError", func(t *testing.T) {
+	t.Run("InsertManyCompositePropagatorBaggageOnly", func(t *testing.T) {
 		t.Parallel()
 
-		middleware, bundle := setup(t)
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableBaggagePropagation: true,
+			Propagator:               propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		})
 
-		doInner := func(ctx context.Context) error {
-			return errors.New("error from doInner")
+		member, err := baggage.NewMember("tenant_id", "tenant_123")
+		require.NoError(t, err)
+		bag, err := baggage.New(member)
+		require.NoError(t, err)
+
+		ctx, span := bundle.tracerProvider.Tracer("test").Start(baggage.ContextWithBaggage(ctx, bag), "parent")
+		defer span.End()
+
+		params := []*rivertype.JobInsertParams{{Kind: "no_op"}}
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
 		}
 
-		err := middleware.Work(ctx, &rivertype.JobRow{
-			Attempt: 6,
-			Kind:    "no_op",
-			Queue:   "my_queue",
-		}, doInner)
-		require.EqualError(t, err, "error from doInner")
+		_, err = middleware.InsertMany(ctx, params, doInner)
+		require.NoError(t, err)
 
-		spans := bundle.traceExporter.GetSpans()
-		require.Len(t, spans, 1)
+		var meta map[string]any
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Equal(t, "tenant_id=tenant_123", meta["baggage"])
 
-		span := spans[0]
-		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
+		// Trace propagation wasn't enabled, so no trace context is injected
+		// even though the propagator includes it and a span is active.
+		require.NotContains(t, meta, "traceparent")
+	})
+
+	t.Run("InsertManyEnableInsertJobSpans", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableInsertJobSpans:   true,
+			EnableTracePropagation: true,
+		})
+
+		scheduledAt := time.Now().Add(1 * time.Hour)
+		params := []*rivertype.JobInsertParams{
+			{Kind: "email_send", Priority: 1, Queue: "default"},
+			{Kind: "notification", Priority: 2, Queue: "critical", ScheduledAt: &scheduledAt},
+		}
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 1}},
+				{Job: &rivertype.JobRow{ID: 2}, UniqueSkippedAsDuplicate: true},
+			}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, params, doInner)
+		require.NoError(t, err)
+
+		// Job spans end before their parent batch span.
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 3)
+		var (
+			jobSpans  = spans[0:2]
+			batchSpan = spans[2]
+		)
+		require.Equal(t, "river.insert_many", batchSpan.Name)
+
+		for i, jobSpan := range jobSpans {
+			require.Equal(t, "river.insert", jobSpan.Name)
+			require.Equal(t, trace.SpanKindProducer, jobSpan.SpanKind)
+			require.Equal(t, batchSpan.SpanContext.SpanID(), jobSpan.Parent.SpanID())
+			require.Equal(t, params[i].Kind, getAttribute(t, jobSpan.Attributes, "kind").AsString())
+			require.Equal(t, params[i].Queue, getAttribute(t, jobSpan.Attributes, "queue").AsString())
+			require.Equal(t, int64(params[i].Priority), getAttribute(t, jobSpan.Attributes, "priority").AsInt64())
+			require.Equal(t, int64(i+1), getAttribute(t, jobSpan.Attributes, "id").AsInt64())
+			require.Equal(t, "ok", getAttribute(t, jobSpan.Attributes, "status").AsString())
+			require.Equal(t, codes.Ok, jobSpan.Status.Code)
+
+			// Each job's metadata references its own span rather than the batch's.
+			var meta map[string]any
+			require.NoError(t, json.Unmarshal(params[i].Metadata, &meta))
+			require.Contains(t, meta["traceparent"], jobSpan.SpanContext.SpanID().String())
+		}
+
+		require.False(t, getAttribute(t, jobSpans[0].Attributes, "unique_skipped_as_duplicate").AsBool())
+		require.True(t, getAttribute(t, jobSpans[1].Attributes, "unique_skipped_as_duplicate").AsBool())
+		require.Equal(t, scheduledAt.Format(time.RFC3339), getAttribute(t, jobSpans[1].Attributes, "scheduled_at").AsString())
+	})
+
+	t.Run("InsertManyEnableInsertJobSpansCustomAttributes", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			AttributePaths: []AttributePath{
+				{Key: "tenant_id", Path: "tenant_id"},
+			},
+			EnableInsertJobSpans: true,
+			InsertAttributesFunc: func(params *rivertype.JobInsertParams) []attribute.KeyValue {
+				return []attribute.KeyValue{attribute.Int("max_attempts", params.MaxAttempts)}
+			},
+		})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{
+			{EncodedArgs: []byte(`{"tenant_id":"tenant_123"}`), Kind: "no_op", MaxAttempts: 25},
+		}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 2)
+
+		jobSpan := spans[0]
+		require.Equal(t, "river.insert", jobSpan.Name)
+		require.Equal(t, "tenant_123", getAttribute(t, jobSpan.Attributes, "tenant_id").AsString())
+		require.Equal(t, int64(25), getAttribute(t, jobSpan.Attributes, "max_attempts").AsInt64())
+	})
+
+	t.Run("InsertManyEnableInsertJobSpansError", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{EnableInsertJobSpans: true})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return nil, errors.New("error from doInner")
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, doInner)
+		require.EqualError(t, err, "error from doInner")
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 2)
+
+		jobSpan := spans[0]
+		require.Equal(t, "river.insert", jobSpan.Name)
+		require.Equal(t, "error", getAttribute(t, jobSpan.Attributes, "status").AsString())
+		require.Equal(t, codes.Error, jobSpan.Status.Code)
+		require.Equal(t, "error from doInner", jobSpan.Status.Description)
+	})
+
+	t.Run("InsertManySpanSampler", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableInsertJobSpans: true,
+			SpanSampler: func(params *SpanSamplerParams) bool {
+				require.Equal(t, "insert_many", params.Operation)
+				require.Nil(t, params.Job)
+				return params.InsertParams[0].Kind != "heartbeat"
+			},
+		})
+
+		insertMany := func(kind string, doInner func(ctx context.Context) ([]*rivertype.JobInsertResult, error)) error {
+			_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: kind}}, doInner)
+			return err
+		}
+
+		require.NoError(t, insertMany("heartbeat", func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 123}}}, nil
+		}))
+		require.Empty(t, bundle.traceExporter.GetSpans())
+
+		// Failed inserts get a span even if not sampled.
+		require.EqualError(t, insertMany("heartbeat", func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return nil, errors.New("error from doInner")
+		}), "error from doInner")
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "river.insert_many", spans[0].Name)
+		require.Equal(t, codes.Error, spans[0].Status.Code)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSumByAttrs(t, metrics, "river.insert_many_count", 1, attribute.String("status", "ok"))
+	})
+
+	t.Run("InsertManySpanSamplerDropsChildSpans", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			SpanSampler:            func(params *SpanSamplerParams) bool { return false },
+		})
+
+		parentCtx, parentSpan := bundle.tracerProvider.Tracer("test").Start(ctx, "parent")
+
+		params := []*rivertype.JobInsertParams{{Kind: "heartbeat"}}
+		_, err := middleware.InsertMany(parentCtx, params, func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			_, span := bundle.tracerProvider.Tracer("test").Start(ctx, "child")
+			require.False(t, span.IsRecording())
+			span.End()
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}}, nil
+		})
+		require.NoError(t, err)
+
+		parentSpan.End()
+
+		// Only the caller's span is recorded.
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "parent", spans[0].Name)
+
+		// Jobs still reference the caller's span rather than the unsampled
+		// stand in for the insert.
+		var meta map[string]any
+		require.NoError(t, json.Unmarshal(params[0].Metadata, &meta))
+		require.Contains(t, meta["traceparent"], spans[0].SpanContext.SpanID().String())
+	})
+
+	t.Run("InsertManyHooks", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		manyParams := []*rivertype.JobInsertParams{{Kind: "no_op"}, {Kind: "no_op"}}
+
+		_, err := middleware.InsertMany(ctx, manyParams, func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			for _, params := range manyParams {
+				require.NoError(t, middleware.InsertBegin(ctx, params))
+			}
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 1}}, {Job: &rivertype.JobRow{ID: 2}}}, nil
+		})
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Len(t, spans[0].Events, 1)
+		require.Equal(t, "river.insert_begin", spans[0].Events[0].Name)
+	})
+
+	t.Run("InsertManyPeriodicJobs", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		scheduledAt := time.Now().Add(-5 * time.Second)
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 123}},
+				{Job: &rivertype.JobRow{ID: 124}},
+				{Job: &rivertype.JobRow{ID: 125}},
+			}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{
+			{Kind: "no_op", Metadata: []byte(`{"periodic":true,"river:periodic_job_id":"my_periodic_job"}`), Queue: "default", ScheduledAt: &scheduledAt},
+			{Kind: "no_op", Metadata: []byte(`{"periodic":true}`), Queue: "default", ScheduledAt: &scheduledAt},
+			{Kind: "no_op", Metadata: []byte(`{}`), Queue: "default"},
+		}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 3)
+
+		span := spans[0]
+		require.Equal(t, "river.periodic_job_enqueue", span.Name)
+		require.Equal(t, trace.SpanKindProducer, span.SpanKind)
+		require.Equal(t, int64(123), getAttribute(t, span.Attributes, "id").AsInt64())
+		require.Equal(t, "my_periodic_job", getAttribute(t, span.Attributes, "periodic_job_id").AsString())
+		require.Equal(t, "ok", getAttribute(t, span.Attributes, "status").AsString())
+		require.Equal(t, scheduledAt.Format(time.RFC3339), getAttribute(t, span.Attributes, "scheduled_at").AsString())
+		require.GreaterOrEqual(t, getAttribute(t, span.Attributes, "enqueue_delay").AsFloat64(), 5.0)
+
+		span = spans[1]
+		require.Equal(t, "river.periodic_job_enqueue", span.Name)
+		require.Equal(t, int64(124), getAttribute(t, span.Attributes, "id").AsInt64())
+		for _, attr := range span.Attributes {
+			require.NotEqual(t, attribute.Key("periodic_job_id"), attr.Key)
+		}
+
+		require.Equal(t, "river.insert_many", spans[2].Name)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSumByAttrs(t, metrics, "river.periodic_job_enqueue_count", 1, attribute.String("periodic_job_id", "my_periodic_job"))
+		_, delay := requireMetric[metricdata.Histogram[float64]](t, metrics, "river.periodic_job_enqueue_delay")
+		require.Len(t, delay.DataPoints, 2) // with and without periodic_job_id
+		for _, dataPoint := range delay.DataPoints {
+			require.Equal(t, uint64(1), dataPoint.Count)
+			minDelay, _ := dataPoint.Min.Value()
+			require.GreaterOrEqual(t, minDelay, 5.0)
+		}
+	})
+
+	t.Run("InsertManyDurationUnitMS", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			DurationUnit: "ms",
+		})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 123}},
+			}, nil
+		}
+
+		insertRes, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, doInner)
+		require.NoError(t, err)
+		require.Equal(t, []*rivertype.JobInsertResult{
+			{Job: &rivertype.JobRow{ID: 123}},
+		}, insertRes)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.insert_count", 1)
+		requireSum(t, metrics, "river.insert_many_count", 1)
+		{
+			metric, _ := requireGaugeNotEmpty(t, metrics, "river.insert_many_duration")
+			require.Equal(t, "ms", metric.Unit)
+		}
+		{
+			metric, _ := requireHistogramCount(t, metrics, "river.insert_many_duration_histogram", 1)
+			require.Equal(t, "ms", metric.Unit)
+		}
+	})
+
+	t.Run("InsertManyEnableSemanticMetrics", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableSemanticMetrics: true,
+		})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 123}},
+			}, nil
+		}
+
+		insertRes, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, doInner)
+		require.NoError(t, err)
+		require.Equal(t, []*rivertype.JobInsertResult{
+			{Job: &rivertype.JobRow{ID: 123}},
+		}, insertRes)
+
+		var (
+			expectedAttrs = []attribute.KeyValue{
+				attribute.String("messaging.operation.name", "insert_many"),
+				attribute.String("messaging.system", "river"),
+			}
+			metrics metricdata.ResourceMetrics
+		)
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "messaging.client.sent.messages", 1, expectedAttrs...)
+		{
+			metric, _ := requireHistogramCount(t, metrics, "messaging.client.operation.duration", 1, expectedAttrs...)
+			require.Equal(t, "s", metric.Unit)
+		}
+	})
+
+	t.Run("InsertManyEnableSemanticSpans", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableInsertJobSpans: true,
+			EnableSemanticSpans:  true,
+		})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 123}},
+				{Job: &rivertype.JobRow{ID: 124}},
+			}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{
+			{Kind: "no_op", Queue: "my_queue"},
+			{Kind: "no_op", Queue: "my_queue"},
+		}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 3)
+
+		for i, jobSpan := range spans[:2] {
+			require.Equal(t, "create my_queue", jobSpan.Name)
+			require.Equal(t, "create", getAttribute(t, jobSpan.Attributes, "messaging.operation.type").AsString())
+			require.Equal(t, "my_queue", getAttribute(t, jobSpan.Attributes, "messaging.destination.name").AsString())
+			require.Equal(t, strconv.Itoa(123+i), getAttribute(t, jobSpan.Attributes, "messaging.message.id").AsString())
+		}
+
+		span := spans[2]
+		require.Equal(t, "send my_queue", span.Name)
+		require.Equal(t, int64(2), getAttribute(t, span.Attributes, "messaging.batch.message_count").AsInt64())
+		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "messaging.destination.name").AsString())
+		require.Equal(t, "insert_many", getAttribute(t, span.Attributes, "messaging.operation.name").AsString())
+		require.Equal(t, "send", getAttribute(t, span.Attributes, "messaging.operation.type").AsString())
+		require.Equal(t, "river", getAttribute(t, span.Attributes, "messaging.system").AsString())
+	})
+
+	t.Run("InsertManyEnableSemanticSpansMultipleQueues", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableSemanticSpans: true,
+		})
+
+		doInner := func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{
+				{Job: &rivertype.JobRow{ID: 123}},
+				{Job: &rivertype.JobRow{ID: 124}},
+			}, nil
+		}
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{
+			{Kind: "no_op", Queue: "my_queue"},
+			{Kind: "no_op", Queue: "other_queue"},
+		}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, "send", span.Name)
+		for _, attr := range span.Attributes {
+			require.NotEqual(t, attribute.Key("messaging.destination.name"), attr.Key)
+			require.NotEqual(t, attribute.Key("messaging.message.id"), attr.Key)
+		}
+	})
+
+	t.Run("WorkSuccess", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		doInner := func(ctx context.Context) error {
+			return nil
+		}
+
+		var (
+			createdAt   = time.Now()
+			scheduledAt = time.Now().Add(1 * time.Second)
+		)
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			ID:          123,
+			Attempt:     6,
+			CreatedAt:   createdAt,
+			Kind:        "no_op",
+			Priority:    1,
+			Queue:       "my_queue",
+			ScheduledAt: scheduledAt,
+			Tags:        []string{"a", "b"},
+		}, doInner)
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, int64(123), getAttribute(t, span.Attributes, "id").AsInt64())
+		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
+		require.Equal(t, createdAt.Format(time.RFC3339), getAttribute(t, span.Attributes, "created_at").AsString())
+		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "queue").AsString())
+		require.Equal(t, int64(1), getAttribute(t, span.Attributes, "priority").AsInt64())
+		require.Equal(t, "ok", getAttribute(t, span.Attributes, "status").AsString())
+		require.Equal(t, scheduledAt.Format(time.RFC3339), getAttribute(t, span.Attributes, "scheduled_at").AsString())
+		require.Equal(t, []string{"a", "b"}, getAttribute(t, span.Attributes, "tag").AsStringSlice())
+		require.Equal(t, "river.work", span.Name)
+		require.Equal(t, codes.Ok, span.Status.Code)
+
+		var (
+			expectedAttrs = []attribute.KeyValue{
+				attribute.String("status", "ok"),
+			}
+			metrics metricdata.ResourceMetrics
+		)
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
+		{
+			metric, _ := requireGaugeNotEmpty(t, metrics, "river.work_duration", expectedAttrs...)
+			require.Equal(t, "s", metric.Unit)
+		}
+		{
+			metric, _ := requireHistogramCount(t, metrics, "river.work_duration_histogram", 1, expectedAttrs...)
+			require.Equal(t, "s", metric.Unit)
+		}
+
+		// Requires EnableSemanticMetrics.
+		requireNoMetric(t, metrics, "messaging.client.consumed.messages")
+		requireNoMetric(t, metrics, "messaging.client.operation.duration")
+		requireNoMetric(t, metrics, "messaging.process.duration")
+	})
+
+	t.Run("WorkMetricAttributeFilter", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			MetricAttributeFilter: attribute.NewDenyKeysFilter("queue", "tag"),
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Kind:        "no_op",
+			Queue:       "my_queue",
+			ScheduledAt: time.Now(),
+			Tags:        []string{"customer_123"},
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		// Filtered attributes are still set on spans.
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "my_queue", getAttribute(t, spans[0].Attributes, "queue").AsString())
+		require.Equal(t, []string{"customer_123"}, getAttribute(t, spans[0].Attributes, "tag").AsStringSlice())
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		{
+			metric, _ := requireSum(t, metrics, "river.work_count", 1, attribute.String("kind", "no_op"))
+			requireNoAttributeKeys(t, metric, "queue", "tag")
+		}
+		{
+			metric, _ := requireHistogramCount(t, metrics, "river.work_duration_histogram", 1, attribute.String("kind", "no_op"))
+			requireNoAttributeKeys(t, metric, "queue", "tag")
+		}
+		{
+			metric, _ := requireHistogramCount(t, metrics, "river.queue_latency", 1, attribute.String("kind", "no_op"))
+			requireNoAttributeKeys(t, metric, "queue")
+		}
+	})
+
+	t.Run("WorkAttributesFunc", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			AttributesFunc: func(job *rivertype.JobRow) []attribute.KeyValue {
+				return []attribute.KeyValue{
+					attribute.Int("max_attempts", job.MaxAttempts),
+				}
+			},
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Kind:        "no_op",
+			MaxAttempts: 25,
+			ScheduledAt: time.Now(),
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, int64(25), getAttribute(t, spans[0].Attributes, "max_attempts").AsInt64())
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.work_count", 1, attribute.Int("max_attempts", 25))
+		requireHistogramCount(t, metrics, "river.queue_latency", 1, attribute.Int("max_attempts", 25))
+	})
+
+	t.Run("WorkAttributePaths", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			AttributePaths: []AttributePath{
+				{Key: "tenant_id", Path: "tenant_id", IncludeInMetrics: true},
+				{Key: "workflow_id", Path: "workflow.id"},
+				{Key: "workflow", Path: "workflow"},
+				{Key: "ratio", Path: "ratio"},
+				{Key: "urgent", Path: "urgent"},
+				{Key: "request_id", Path: "request_id", Source: "metadata"},
+				{Key: "missing", Path: "missing"},
+			},
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			EncodedArgs: []byte(`{"ratio":0.5,"tenant_id":"tenant_123","urgent":true,"workflow":{"id":456}}`),
+			Kind:        "no_op",
+			Metadata:    []byte(`{"request_id":"req_789"}`),
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		require.Equal(t, "tenant_123", getAttribute(t, span.Attributes, "tenant_id").AsString())
+		require.Equal(t, int64(456), getAttribute(t, span.Attributes, "workflow_id").AsInt64())
+		require.JSONEq(t, `{"id":456}`, getAttribute(t, span.Attributes, "workflow").AsString())
+		require.InDelta(t, 0.5, getAttribute(t, span.Attributes, "ratio").AsFloat64(), 0.001)
+		require.True(t, getAttribute(t, span.Attributes, "urgent").AsBool())
+		require.Equal(t, "req_789", getAttribute(t, span.Attributes, "request_id").AsString())
+		for _, attr := range span.Attributes {
+			require.NotEqual(t, attribute.Key("missing"), attr.Key)
+		}
+
+		// Only attributes with IncludeInMetrics go to metrics.
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		metric, _ := requireSum(t, metrics, "river.work_count", 1, attribute.String("tenant_id", "tenant_123"))
+		requireNoAttributeKeys(t, metric, "workflow_id", "workflow", "ratio", "urgent", "request_id")
+	})
+
+	t.Run("AttributePathInvalid", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "attribute path must have a key and path", func() {
+			NewMiddleware(&MiddlewareConfig{AttributePaths: []AttributePath{{Key: "tenant_id"}}})
+		})
+		require.PanicsWithValue(t, "attribute path source must be one of args or metadata", func() {
+			NewMiddleware(&MiddlewareConfig{AttributePaths: []AttributePath{{Key: "tenant_id", Path: "tenant_id", Source: "tags"}}})
+		})
+	})
+
+	t.Run("WorkQueueLatency", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Kind:        "no_op",
+			Queue:       "my_queue",
+			ScheduledAt: time.Now().Add(-2 * time.Second),
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		metric, metricData := requireHistogramCount(t, metrics, "river.queue_latency", 1,
+			attribute.String("kind", "no_op"),
+			attribute.String("queue", "my_queue"),
+		)
+		require.Equal(t, "s", metric.Unit)
+		require.GreaterOrEqual(t, metricData.DataPoints[0].Sum, 2.0)
+		require.Less(t, metricData.DataPoints[0].Sum, 60.0)
+	})
+
+	t.Run("WorkQueueLatencyScheduledInFuture", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		// Possible because of clock drift between a client and the database.
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Kind:        "no_op",
+			ScheduledAt: time.Now().Add(1 * time.Minute),
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		_, metricData := requireHistogramCount(t, metrics, "river.queue_latency", 1)
+		require.Zero(t, metricData.DataPoints[0].Sum)
+	})
+
+	t.Run("WorkError", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		doInner := func(ctx context.Context) error {
+			return errors.New("error from doInner")
+		}
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Attempt: 6,
+			Kind:    "no_op",
+			Queue:   "my_queue",
+		}, doInner)
+		require.EqualError(t, err, "error from doInner")
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
 		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "queue").AsString())
 		require.Equal(t, "error", getAttribute(t, span.Attributes, "status").AsString())
 		require.Equal(t, "river.work", span.Name)
 		require.Equal(t, codes.Error, span.Status.Code)
 		require.Equal(t, "error from doInner", span.Status.Description)
 
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "exception", span.Events[0].Name)
+		require.Equal(t, "error from doInner", getAttribute(t, span.Events[0].Attributes, "exception.message").AsString())
+		require.Equal(t, "*errors.errorString", getAttribute(t, span.Events[0].Attributes, "exception.type").AsString())
+
 		var (
 			expectedAttrs = []attribute.KeyValue{
 				attribute.String("status", "error"),
@@ -710,61 +1513,414 @@ func TestMiddleware(t *testing.T) {
 		spans := bundle.traceExporter.GetSpans()
 		require.Len(t, spans, 1)
 
-		span := spans[0]
-		require.True(t, getAttribute(t, span.Attributes, "snooze").AsBool())
-		require.Equal(t, "ok", getAttribute(t, span.Attributes, "status").AsString())
-		require.Equal(t, codes.Ok, span.Status.Code)
-		require.Equal(t, "7s", getAttribute(t, span.Attributes, "snooze.duration").AsString())
+		span := spans[0]
+		require.True(t, getAttribute(t, span.Attributes, "snooze").AsBool())
+		require.Equal(t, "ok", getAttribute(t, span.Attributes, "status").AsString())
+		require.Equal(t, codes.Ok, span.Status.Code)
+		require.Equal(t, "7s", getAttribute(t, span.Attributes, "snooze.duration").AsString())
+
+		var (
+			expectedAttrs = []attribute.KeyValue{
+				attribute.String("status", "ok"),
+				attribute.Bool("snooze", true),
+			}
+			metrics metricdata.ResourceMetrics
+		)
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
+	})
+
+	t.Run("WorkPanic", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		doInner := func(ctx context.Context) error {
+			panic("panic from doInner")
+		}
+
+		require.PanicsWithValue(t, "panic from doInner", func() {
+			_ = middleware.Work(ctx, &rivertype.JobRow{
+				Attempt: 6,
+				Kind:    "no_op",
+				Queue:   "my_queue",
+			}, doInner)
+		})
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
+		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "queue").AsString())
+		require.Equal(t, "panic", getAttribute(t, span.Attributes, "status").AsString())
+		require.Equal(t, "river.work", span.Name)
+		require.Equal(t, codes.Error, span.Status.Code)
+		require.Equal(t, "panic", span.Status.Description)
+
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "exception", span.Events[0].Name)
+		require.Equal(t, "panic from doInner", getAttribute(t, span.Events[0].Attributes, "exception.message").AsString())
+		require.Equal(t, "string", getAttribute(t, span.Events[0].Attributes, "exception.type").AsString())
+		require.Contains(t, getAttribute(t, span.Events[0].Attributes, "exception.stacktrace").AsString(), "TestMiddleware")
+
+		var (
+			expectedAttrs = []attribute.KeyValue{
+				attribute.String("status", "panic"),
+			}
+			metrics metricdata.ResourceMetrics
+		)
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
+		requireGaugeNotEmpty(t, metrics, "river.work_duration", expectedAttrs...)
+		requireHistogramCount(t, metrics, "river.work_duration_histogram", 1, expectedAttrs...)
+	})
+
+	t.Run("WorkPanicError", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		panicErr := errors.New("error panic from doInner")
+
+		doInner := func(ctx context.Context) error {
+			panic(panicErr)
+		}
+
+		require.PanicsWithError(t, "error panic from doInner", func() {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op"}, doInner)
+		})
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "error panic from doInner", getAttribute(t, span.Events[0].Attributes, "exception.message").AsString())
+		require.Equal(t, "*errors.errorString", getAttribute(t, span.Events[0].Attributes, "exception.type").AsString())
+	})
+
+	t.Run("WorkOutcome", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		work := func(attempt int, doInner func(ctx context.Context) error) {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Attempt: attempt, Kind: "no_op", MaxAttempts: 3}, doInner)
+		}
+
+		work(1, func(ctx context.Context) error { return nil })
+		work(1, func(ctx context.Context) error { return errors.New("error from doInner") })
+		work(3, func(ctx context.Context) error { return errors.New("error from doInner") })
+		work(3, func(ctx context.Context) error { return rivertype.JobCancel(errors.New("cancelled")) })
+		work(3, func(ctx context.Context) error { return &rivertype.JobSnoozeError{Duration: time.Minute} })
+		require.Panics(t, func() {
+			work(1, func(ctx context.Context) error { panic("panic from doInner") })
+		})
+
+		var outcomes []string
+		for _, span := range bundle.traceExporter.GetSpans() {
+			outcomes = append(outcomes, getAttribute(t, span.Attributes, "outcome").AsString())
+		}
+		require.Equal(t, []string{"completed", "retryable", "discarded", "cancelled", "snoozed", "panicked"}, outcomes)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		for _, outcome := range outcomes {
+			requireSumByAttrs(t, metrics, "river.work_count", 1, attribute.String("outcome", outcome))
+		}
+		requireSum(t, metrics, "river.job_discarded_count", 1, attribute.String("kind", "no_op"), attribute.String("outcome", "discarded"))
+	})
+
+	t.Run("WorkOutcomePanicOnFinalAttempt", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		require.Panics(t, func() {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Attempt: 3, Kind: "no_op", MaxAttempts: 3}, func(ctx context.Context) error {
+				panic("panic from doInner")
+			})
+		})
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSum(t, metrics, "river.job_discarded_count", 1, attribute.String("outcome", "panicked"))
+	})
+
+	t.Run("WorkHistogramBucketBoundaries", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			HistogramBucketBoundaries: map[string][]float64{
+				"river.work_duration_histogram": {0.001, 0.01, 1, 3600},
+			},
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op", ScheduledAt: time.Now()}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+
+		_, workDuration := requireMetric[metricdata.Histogram[float64]](t, metrics, "river.work_duration_histogram")
+		require.Equal(t, []float64{0.001, 0.01, 1, 3600}, workDuration.DataPoints[0].Bounds)
+
+		// Histograms without configured boundaries get the SDK's defaults.
+		_, queueLatency := requireMetric[metricdata.Histogram[float64]](t, metrics, "river.queue_latency")
+		require.NotEqual(t, []float64{0.001, 0.01, 1, 3600}, queueLatency.DataPoints[0].Bounds)
+	})
+
+	t.Run("HistogramBucketBoundariesInvalid", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "histogram bucket boundaries given for unknown histogram: messaging.client.sent.messages", func() {
+			NewMiddleware(&MiddlewareConfig{HistogramBucketBoundaries: map[string][]float64{"messaging.client.sent.messages": {1, 2}}})
+		})
+		require.PanicsWithValue(t, "histogram bucket boundaries must be strictly increasing: river.work_duration_histogram", func() {
+			NewMiddleware(&MiddlewareConfig{HistogramBucketBoundaries: map[string][]float64{"river.work_duration_histogram": {2, 1}}})
+		})
+	})
+
+	t.Run("WorkSpanSampler", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			SpanSampler: func(params *SpanSamplerParams) bool {
+				require.Equal(t, "work", params.Operation)
+				require.Nil(t, params.InsertParams)
+				return params.Job.Kind != "heartbeat"
+			},
+		})
+
+		work := func(kind string, doInner func(ctx context.Context) error) {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Attempt: 1, Kind: kind, MaxAttempts: 25}, doInner)
+		}
+
+		work("heartbeat", func(ctx context.Context) error {
+			require.False(t, trace.SpanFromContext(ctx).SpanContext().IsSampled())
+			return nil
+		})
+		work("heartbeat", func(ctx context.Context) error { return &rivertype.JobSnoozeError{Duration: time.Minute} })
+		work("payment", func(ctx context.Context) error { return nil })
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "payment", getAttribute(t, spans[0].Attributes, "kind").AsString())
+
+		// Metrics are recorded regardless of sampling.
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireSumByAttrs(t, metrics, "river.work_count", 2, attribute.String("kind", "heartbeat"))
+	})
+
+	t.Run("WorkSpanSamplerDropsChildSpans", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			SpanSampler: func(params *SpanSamplerParams) bool { return false },
+		})
+
+		require.NoError(t, middleware.Work(ctx, &rivertype.JobRow{Attempt: 1, Kind: "heartbeat"}, func(ctx context.Context) error {
+			_, span := bundle.tracerProvider.Tracer("test").Start(ctx, "child")
+			require.False(t, span.IsRecording())
+			span.End()
+			return nil
+		}))
+
+		require.Empty(t, bundle.traceExporter.GetSpans())
+	})
+
+	t.Run("WorkSpanSamplerDropsChildSpansOfRemoteParent", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			SpanSampler:            func(params *SpanSamplerParams) bool { return false },
+			TracePropagationMode:   "parent",
+		})
+
+		const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+		require.NoError(t, middleware.Work(ctx, &rivertype.JobRow{
+			Attempt:  1,
+			Kind:     "heartbeat",
+			Metadata: []byte(`{"traceparent":"` + traceparent + `"}`),
+		}, func(ctx context.Context) error {
+			spanCtx := trace.SpanContextFromContext(ctx)
+			require.Equal(t, "0af7651916cd43dd8448eb211c80319c", spanCtx.TraceID().String())
+			require.False(t, spanCtx.IsSampled())
+
+			_, span := bundle.tracerProvider.Tracer("test").Start(ctx, "child")
+			span.End()
+			return nil
+		}))
+
+		require.Empty(t, bundle.traceExporter.GetSpans())
+	})
+
+	t.Run("WorkSpanSamplerFailedAttempt", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			AttributePaths: []AttributePath{{Key: "tenant_id", Path: "tenant_id"}},
+			SpanSampler:    func(params *SpanSamplerParams) bool { return false },
+		})
+
+		begin := time.Now()
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Attempt:     1,
+			EncodedArgs: []byte(`{"tenant_id":"tenant_123"}`),
+			Kind:        "heartbeat",
+			MaxAttempts: 25,
+		}, func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return errors.New("error from doInner")
+		})
+		require.EqualError(t, err, "error from doInner")
+
+		require.Panics(t, func() {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Kind: "heartbeat"}, func(ctx context.Context) error {
+				panic("panic from doInner")
+			})
+		})
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 2)
+
+		span := spans[0]
+		require.Equal(t, "river.work", span.Name)
+		require.Equal(t, codes.Error, span.Status.Code)
+		require.Equal(t, "retryable", getAttribute(t, span.Attributes, "outcome").AsString())
+		require.Equal(t, "tenant_123", getAttribute(t, span.Attributes, "tenant_id").AsString())
+		require.WithinRange(t, span.StartTime, begin, begin.Add(10*time.Millisecond))
+		require.GreaterOrEqual(t, span.EndTime.Sub(span.StartTime), 10*time.Millisecond)
+
+		span = spans[1]
+		require.Equal(t, "panic", getAttribute(t, span.Attributes, "status").AsString())
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "exception", span.Events[0].Name)
+	})
+
+	t.Run("WorkHooks", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		job := &rivertype.JobRow{Kind: "no_op"}
+
+		// Invoke hooks the same way River's executor does from within the
+		// middleware stack.
+		err := middleware.Work(ctx, job, func(ctx context.Context) error {
+			require.NoError(t, middleware.WorkBegin(ctx, job))
+			time.Sleep(10 * time.Millisecond) // simulated work
+			return middleware.WorkEnd(ctx, job, errors.New("error from worker"))
+		})
+		require.EqualError(t, err, "error from worker")
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Len(t, span.Events, 3)
+		require.Equal(t, "river.work_begin", span.Events[0].Name)
+		require.Equal(t, "river.work_end", span.Events[1].Name)
+		require.Equal(t, "exception", span.Events[2].Name)
+		require.GreaterOrEqual(t, span.Events[1].Time.Sub(span.Events[0].Time), 10*time.Millisecond)
+
+		require.GreaterOrEqual(t, getAttribute(t, span.Attributes, "duration.before_work").AsFloat64(), 0.0)
+		require.GreaterOrEqual(t, getAttribute(t, span.Attributes, "duration.work").AsFloat64(), 0.01)
+		require.GreaterOrEqual(t, getAttribute(t, span.Attributes, "duration.after_work").AsFloat64(), 0.0)
+	})
+
+	t.Run("WorkHooksOutsideWork", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, _ := setup(t)
+
+		job := &rivertype.JobRow{Kind: "no_op"}
+
+		require.NoError(t, middleware.WorkBegin(ctx, job))
+		require.EqualError(t, middleware.WorkEnd(ctx, job, errors.New("error from worker")), "error from worker")
+	})
+
+	t.Run("WorkLogs", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setup(t)
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Attempt:     1,
+			ID:          123,
+			Kind:        "no_op",
+			MaxAttempts: 25,
+			Queue:       "my_queue",
+		}, func(ctx context.Context) error { return errors.New("error from doInner") })
+		require.EqualError(t, err, "error from doInner")
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		records := bundle.logProcessor.Records()
+		require.Len(t, records, 2)
 
-		var (
-			expectedAttrs = []attribute.KeyValue{
-				attribute.String("status", "ok"),
-				attribute.Bool("snooze", true),
-			}
-			metrics metricdata.ResourceMetrics
-		)
-		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
-		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
+		require.Equal(t, "river.job.start", records[0].EventName())
+		require.Equal(t, log.SeverityInfo, records[0].Severity())
+		require.Equal(t, int64(123), getLogAttribute(t, records[0], "id").AsInt64())
+		require.Equal(t, "no_op", getLogAttribute(t, records[0], "kind").AsString())
+		require.Equal(t, "my_queue", getLogAttribute(t, records[0], "queue").AsString())
+
+		require.Equal(t, "river.job.failure", records[1].EventName())
+		require.Equal(t, log.SeverityWarn, records[1].Severity())
+		require.Equal(t, "error from doInner", getLogAttribute(t, records[1], "exception.message").AsString())
+
+		// Records are correlated with the work span.
+		for _, record := range records {
+			require.Equal(t, spans[0].SpanContext.TraceID(), record.TraceID())
+			require.Equal(t, spans[0].SpanContext.SpanID(), record.SpanID())
+		}
 	})
 
-	t.Run("WorkPanic", func(t *testing.T) {
+	t.Run("WorkLogsOutcomes", func(t *testing.T) {
 		t.Parallel()
 
 		middleware, bundle := setup(t)
 
-		doInner := func(ctx context.Context) error {
-			panic("panic from doInner")
+		work := func(attempt int, doInner func(ctx context.Context) error) {
+			_ = middleware.Work(ctx, &rivertype.JobRow{Attempt: attempt, Kind: "no_op", MaxAttempts: 3}, doInner)
 		}
 
-		require.PanicsWithValue(t, "panic from doInner", func() {
-			_ = middleware.Work(ctx, &rivertype.JobRow{
-				Attempt: 6,
-				Kind:    "no_op",
-				Queue:   "my_queue",
-			}, doInner)
+		work(1, func(ctx context.Context) error { return nil })
+		work(1, func(ctx context.Context) error { return rivertype.JobCancel(errors.New("cancelled")) })
+		work(1, func(ctx context.Context) error { return &rivertype.JobSnoozeError{Duration: time.Minute} })
+		work(3, func(ctx context.Context) error { return errors.New("error from doInner") })
+		require.Panics(t, func() {
+			work(1, func(ctx context.Context) error { panic("panic from doInner") })
 		})
 
-		spans := bundle.traceExporter.GetSpans()
-		require.Len(t, spans, 1)
+		var eventNames []string
+		for _, record := range bundle.logProcessor.Records() {
+			if record.EventName() != "river.job.start" {
+				eventNames = append(eventNames, record.EventName())
+			}
+		}
+		require.Equal(t, []string{"river.job.cancel", "river.job.snooze", "river.job.discard", "river.job.failure"}, eventNames)
+	})
 
-		span := spans[0]
-		require.Equal(t, int64(6), getAttribute(t, span.Attributes, "attempt").AsInt64())
-		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "queue").AsString())
-		require.Equal(t, "panic", getAttribute(t, span.Attributes, "status").AsString())
-		require.Equal(t, "river.work", span.Name)
-		require.Equal(t, codes.Error, span.Status.Code)
-		require.Equal(t, "panic", span.Status.Description)
+	t.Run("WorkLogsDisabled", func(t *testing.T) {
+		t.Parallel()
 
-		var (
-			expectedAttrs = []attribute.KeyValue{
-				attribute.String("status", "panic"),
-			}
-			metrics metricdata.ResourceMetrics
-		)
-		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
-		requireSum(t, metrics, "river.work_count", 1, expectedAttrs...)
-		requireGaugeNotEmpty(t, metrics, "river.work_duration", expectedAttrs...)
-		requireHistogramCount(t, metrics, "river.work_duration_histogram", 1, expectedAttrs...)
+		middleware := NewMiddleware(&MiddlewareConfig{
+			MeterProvider:  metric.NewMeterProvider(),
+			TracerProvider: sdktrace.NewTracerProvider(),
+		})
+		require.Nil(t, middleware.logger)
+
+		err := middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op"}, func(ctx context.Context) error {
+			return errors.New("error from doInner")
+		})
+		require.EqualError(t, err, "error from doInner")
 	})
 
 	// Make sure the middleware can fall back to a global provider.
@@ -781,6 +1937,60 @@ func TestMiddleware(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("DurationMetricsGauge", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			DurationMetrics: "gauge",
+		})
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 123}}}, nil
+		})
+		require.NoError(t, err)
+
+		err = middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op"}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireGaugeNotEmpty(t, metrics, "river.insert_many_duration")
+		requireGaugeNotEmpty(t, metrics, "river.work_duration")
+		requireNoMetric(t, metrics, "river.insert_many_duration_histogram")
+		requireNoMetric(t, metrics, "river.work_duration_histogram")
+	})
+
+	t.Run("DurationMetricsHistogram", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			DurationMetrics: "histogram",
+		})
+
+		_, err := middleware.InsertMany(ctx, []*rivertype.JobInsertParams{{Kind: "no_op"}}, func(ctx context.Context) ([]*rivertype.JobInsertResult, error) {
+			return []*rivertype.JobInsertResult{{Job: &rivertype.JobRow{ID: 123}}}, nil
+		})
+		require.NoError(t, err)
+
+		err = middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op"}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		var metrics metricdata.ResourceMetrics
+		require.NoError(t, bundle.metricReader.Collect(ctx, &metrics))
+		requireHistogramCount(t, metrics, "river.insert_many_duration_histogram", 1)
+		requireHistogramCount(t, metrics, "river.work_duration_histogram", 1)
+		requireNoMetric(t, metrics, "river.insert_many_duration")
+		requireNoMetric(t, metrics, "river.work_duration")
+	})
+
+	t.Run("DurationMetricsInvalid", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "duration metrics must be one of both, gauge, or histogram", func() {
+			NewMiddleware(&MiddlewareConfig{DurationMetrics: "summary"})
+		})
+	})
+
 	t.Run("WorkDurationUnitMS", func(t *testing.T) {
 		t.Parallel()
 
@@ -888,6 +2098,187 @@ func TestMiddleware(t *testing.T) {
 		require.False(t, spans[0].Parent.IsValid(), "expected no parent span when metadata has no traceparent")
 	})
 
+	t.Run("WorkExtractsTraceparentMetadataPath", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation:       true,
+			TracePropagationMetadataPath: "otel",
+		})
+
+		for _, metadata := range []string{
+			// Stored at the configured path.
+			`{"otel":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}`,
+
+			// Legacy top-level key from before a path was configured.
+			`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`,
+
+			// Configured path takes precedence over a top-level key.
+			`{"otel":{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},"traceparent":"00-11111111111111111111111111111111-1111111111111111-01"}`,
+		} {
+			err := middleware.Work(ctx, &rivertype.JobRow{
+				Kind:     "no_op",
+				Metadata: []byte(metadata),
+			}, func(ctx context.Context) error { return nil })
+			require.NoError(t, err)
+		}
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 3)
+		for _, span := range spans {
+			require.Len(t, span.Links, 1)
+			require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.Links[0].SpanContext.TraceID().String())
+			require.Equal(t, "00f067aa0ba902b7", span.Links[0].SpanContext.SpanID().String())
+		}
+	})
+
+	t.Run("WorkExtractsTraceparentModeParent", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			TracePropagationMode:   "parent",
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Attempt:   3,
+			CreatedAt: time.Now().Add(-24 * time.Hour),
+			Kind:      "no_op",
+			Metadata:  []byte(`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`),
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		workSpan := spans[0]
+
+		require.Empty(t, workSpan.Links)
+		require.True(t, workSpan.Parent.IsRemote())
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", workSpan.Parent.TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", workSpan.Parent.SpanID().String())
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", workSpan.SpanContext.TraceID().String())
+	})
+
+	t.Run("WorkExtractsTraceparentModeParentIfRecent", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation:         true,
+			TracePropagationMode:           "parent_if_recent",
+			TracePropagationParentMaxDelay: 1 * time.Minute,
+		})
+
+		metadata := []byte(`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`)
+
+		for _, job := range []*rivertype.JobRow{
+			{Attempt: 1, CreatedAt: time.Now(), Kind: "recent", Metadata: metadata},
+			{Attempt: 1, CreatedAt: time.Now().Add(-1 * time.Hour), Kind: "delayed", Metadata: metadata},
+			{Attempt: 2, CreatedAt: time.Now(), Kind: "retried", Metadata: metadata},
+		} {
+			require.NoError(t, middleware.Work(ctx, job, func(ctx context.Context) error { return nil }))
+		}
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 3)
+
+		// Recent first attempt is a child of the enqueuing span.
+		require.Equal(t, "recent", getAttribute(t, spans[0].Attributes, "kind").AsString())
+		require.True(t, spans[0].Parent.IsValid())
+		require.Empty(t, spans[0].Links)
+
+		// Delayed and retried jobs are linked instead.
+		for _, span := range spans[1:] {
+			require.False(t, span.Parent.IsValid())
+			require.Len(t, span.Links, 1)
+			require.Equal(t, "00f067aa0ba902b7", span.Links[0].SpanContext.SpanID().String())
+		}
+	})
+
+	t.Run("TracePropagationModeInvalid", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "trace propagation mode must be one of link, parent, or parent_if_recent", func() {
+			NewMiddleware(&MiddlewareConfig{TracePropagationMode: "sibling"})
+		})
+		require.PanicsWithValue(t, "trace propagation parent max delay must be greater than zero with trace propagation mode parent_if_recent", func() {
+			NewMiddleware(&MiddlewareConfig{TracePropagationMode: "parent_if_recent"})
+		})
+	})
+
+	t.Run("WorkExtractsWithPropagator", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableTracePropagation: true,
+			Propagator:             &testPropagator{},
+		})
+
+		parentTraceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID := "00f067aa0ba902b7"
+		metadata, err := json.Marshal(map[string]any{
+			testPropagatorKey: parentTraceID + "-" + parentSpanID,
+		})
+		require.NoError(t, err)
+
+		err = middleware.Work(ctx, &rivertype.JobRow{
+			Kind:     "no_op",
+			Metadata: metadata,
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		workSpan := spans[0]
+
+		require.Len(t, workSpan.Links, 1)
+		require.Equal(t, parentTraceID, workSpan.Links[0].SpanContext.TraceID().String())
+		require.Equal(t, parentSpanID, workSpan.Links[0].SpanContext.SpanID().String())
+	})
+
+	t.Run("WorkExtractsBaggage", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{EnableBaggagePropagation: true})
+
+		metadata, err := json.Marshal(map[string]any{
+			"baggage":     "tenant_id=tenant_123,request_id=req_456",
+			"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		})
+		require.NoError(t, err)
+
+		var workBag baggage.Baggage
+		err = middleware.Work(ctx, &rivertype.JobRow{
+			Kind:     "no_op",
+			Metadata: metadata,
+		}, func(ctx context.Context) error {
+			workBag = baggage.FromContext(ctx)
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "tenant_123", workBag.Member("tenant_id").Value())
+		require.Equal(t, "req_456", workBag.Member("request_id").Value())
+
+		// Trace propagation wasn't enabled, so traceparent should be ignored.
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Empty(t, spans[0].Links)
+	})
+
+	t.Run("WorkExtractsBaggageMissingMetadata", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, _ := setupConfig(t, &MiddlewareConfig{EnableBaggagePropagation: true})
+
+		var workBag baggage.Baggage
+		err := middleware.Work(ctx, &rivertype.JobRow{Kind: "no_op"}, func(ctx context.Context) error {
+			workBag = baggage.FromContext(ctx)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Zero(t, workBag.Len())
+	})
+
 	t.Run("WorkEnableWorkSpanJobKindSuffix ", func(t *testing.T) {
 		t.Parallel()
 
@@ -911,6 +2302,36 @@ func TestMiddleware(t *testing.T) {
 		span := spans[0]
 		require.Equal(t, "river.work/no_op", span.Name)
 	})
+
+	t.Run("WorkEnableSemanticSpans", func(t *testing.T) {
+		t.Parallel()
+
+		middleware, bundle := setupConfig(t, &MiddlewareConfig{
+			EnableSemanticSpans:         true,
+			EnableWorkSpanJobKindSuffix: true, // has no effect
+		})
+
+		err := middleware.Work(ctx, &rivertype.JobRow{
+			Attempt: 1,
+			ID:      123,
+			Kind:    "no_op",
+			Queue:   "my_queue",
+		}, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+
+		spans := bundle.traceExporter.GetSpans()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, "process my_queue", span.Name)
+		require.Equal(t, trace.SpanKindConsumer, span.SpanKind)
+		require.Equal(t, "my_queue", getAttribute(t, span.Attributes, "messaging.destination.name").AsString())
+		require.Equal(t, "123", getAttribute(t, span.Attributes, "messaging.message.id").AsString())
+		require.Equal(t, "work", getAttribute(t, span.Attributes, "messaging.operation.name").AsString())
+		require.Equal(t, "process", getAttribute(t, span.Attributes, "messaging.operation.type").AsString())
+		require.Equal(t, "river", getAttribute(t, span.Attributes, "messaging.system").AsString())
+		require.Equal(t, "no_op", getAttribute(t, span.Attributes, "kind").AsString())
+	})
 }
 
 type fakeBatchError struct {
@@ -921,6 +2342,101 @@ func (e *fakeBatchError) Error() string { return "batch error" }
 
 func (e *fakeBatchError) ErrorsByID() map[int64]error { return e.errorsByID }
 
+const testPropagatorKey = "x-test-trace"
+
+// testPropagator is a minimal non-W3C propagator that encodes a span context
+// as "<trace ID>-<span ID>" under a single key, standing in for formats like
+// B3 or X-Ray.
+type testPropagator struct{}
+
+func (*testPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(testPropagatorKey, sc.TraceID().String()+"-"+sc.SpanID().String())
+}
+
+func (*testPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	traceIDStr, spanIDStr, ok := strings.Cut(carrier.Get(testPropagatorKey), "-")
+	if !ok {
+		return ctx
+	}
+	traceID, err := trace.TraceIDFromHex(traceIDStr)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(spanIDStr)
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		Remote:     true,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		TraceID:    traceID,
+	}))
+}
+
+func (*testPropagator) Fields() []string { return []string{testPropagatorKey} }
+
+// A metric type unknown to the middleware. Embedding rivertype.Metric lets it
+// satisfy the interface's unexported method.
+type testRiverMetric struct {
+	rivertype.Metric
+
+	Count    int
+	Duration time.Duration
+	Leader   bool
+	Queue    string
+}
+
+func (m *testRiverMetric) Name() rivertype.MetricName { return "test_metric" }
+
+// A metric type unknown to the middleware with no values to record.
+type testRiverMetricNoValues struct {
+	rivertype.Metric
+
+	Queue string
+}
+
+func (m *testRiverMetricNoValues) Name() rivertype.MetricName { return "test_metric_no_values" }
+
+// A metric type with a name that's invalid as an instrument name.
+type testRiverMetricInvalidName struct {
+	rivertype.Metric
+
+	Count int
+}
+
+func (m *testRiverMetricInvalidName) Name() rivertype.MetricName { return "invalid metric name!" }
+
+// testLogProcessor is a log processor that keeps emitted records in memory.
+type testLogProcessor struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *testLogProcessor) Enabled(ctx context.Context, param sdklog.EnabledParameters) bool {
+	return true
+}
+
+func (p *testLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record.Clone())
+	return nil
+}
+
+func (p *testLogProcessor) Records() []sdklog.Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.records)
+}
+
+func (p *testLogProcessor) ForceFlush(ctx context.Context) error { return nil }
+func (p *testLogProcessor) Shutdown(ctx context.Context) error   { return nil }
+
 func getAttribute(t *testing.T, attrs []attribute.KeyValue, key string) attribute.Value {
 	t.Helper()
 
@@ -933,6 +2449,24 @@ func getAttribute(t *testing.T, attrs []attribute.KeyValue, key string) attribut
 	return attribute.Value{}
 }
 
+func getLogAttribute(t *testing.T, record sdklog.Record, key string) log.Value {
+	t.Helper()
+
+	var (
+		found bool
+		value log.Value
+	)
+	record.WalkAttributes(func(attr log.KeyValue) bool {
+		if attr.Key == key {
+			found, value = true, attr.Value
+			return false
+		}
+		return true
+	})
+	require.True(t, found, "log attribute %q not found", key)
+	return value
+}
+
 func getMetric[T metricdatatest.Datatypes](t *testing.T, metrics metricdata.ResourceMetrics, name string) (metricdata.Metrics, T, bool) {
 	t.Helper()
 
@@ -989,6 +2523,36 @@ func requireMetric[T metricdatatest.Datatypes](t *testing.T, metrics metricdata.
 	return metric, metricData
 }
 
+// requireNoAttributeKeys asserts that no data point on the given metric has an
+// attribute with any of the given keys.
+func requireNoAttributeKeys(t *testing.T, metric metricdata.Metrics, keys ...attribute.Key) {
+	t.Helper()
+
+	var sets []attribute.Set
+	switch data := metric.Data.(type) {
+	case metricdata.Gauge[float64]:
+		for _, dataPoint := range data.DataPoints {
+			sets = append(sets, dataPoint.Attributes)
+		}
+	case metricdata.Histogram[float64]:
+		for _, dataPoint := range data.DataPoints {
+			sets = append(sets, dataPoint.Attributes)
+		}
+	case metricdata.Sum[int64]:
+		for _, dataPoint := range data.DataPoints {
+			sets = append(sets, dataPoint.Attributes)
+		}
+	default:
+		require.FailNow(t, fmt.Sprintf("unhandled metric data type: %T", data))
+	}
+
+	for _, set := range sets {
+		for _, key := range keys {
+			require.False(t, set.HasValue(key), "metric %s should not have attribute %s", metric.Name, key)
+		}
+	}
+}
+
 func requireSum(t *testing.T, metrics metricdata.ResourceMetrics, name string, val int64, attrs ...attribute.KeyValue) (metricdata.Metrics, metricdata.Sum[int64]) { //nolint:unparam
 	t.Helper()
 
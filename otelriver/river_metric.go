@@ -0,0 +1,271 @@
+package otelriver
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/riverqueue/river/rivertype"
+)
+
+// Descriptions and units of instruments for metrics River is known to emit
+// through HookMetricEmit, keyed by instrument name. Metrics that aren't listed
+// still get instruments, but with a generic description, and without a unit
+// unless their values are durations.
+var riverMetricInstrumentInfos = map[string]riverMetricInstrumentInfo{ //nolint:gochecknoglobals
+	prefix + "job_get_available_count":    {description: "Number of jobs locked by successful JobGetAvailable calls", unit: "{job}"},
+	prefix + "job_get_available_duration": {description: "Duration of successful JobGetAvailable calls"},
+}
+
+type riverMetricInstrumentInfo struct {
+	description string
+	unit        string
+}
+
+type riverMetricFieldKind int
+
+const (
+	riverMetricFieldKindAttribute riverMetricFieldKind = iota
+	riverMetricFieldKindDuration
+	riverMetricFieldKindFloat
+	riverMetricFieldKindInt
+)
+
+// A field of a River metric struct that's mapped to either an attribute or a
+// value recorded to an instrument.
+type riverMetricField struct {
+	index          int
+	instrumentName string // empty for attributes
+	key            string // field name in snake case
+	kind           riverMetricFieldKind
+}
+
+// How a River metric type maps to instruments, derived by reflecting on its
+// fields once and then cached by type. String and boolean fields become
+// attributes, and each numeric or duration field is recorded to a histogram.
+// Histograms are named after the metric if it has only a single value field,
+// or after the metric and field name like `river.<metric>_<field>` otherwise.
+type riverMetricLayout struct {
+	attributeFields []riverMetricField
+	valueFields     []riverMetricField
+}
+
+// Instruments for metrics River emits through HookMetricEmit. Because River
+// may add new metric types at any time, instruments are created as metrics
+// are first seen rather than up front.
+type riverMetricInstruments struct {
+	float64Histograms sync.Map // instrument name -> metric.Float64Histogram
+	int64Histograms   sync.Map // instrument name -> metric.Int64Histogram
+	layouts           sync.Map // reflect.Type -> *riverMetricLayout (nil if unmappable)
+}
+
+// recordRiverMetric records a metric emitted by River to instruments derived
+// from its name and fields. Metrics that can't be mapped, like ones that
+// aren't structs or that have no numeric fields, are counted in
+// `river.metric_emit_unmapped_count` instead so they're not silently dropped.
+func (m *Middleware) recordRiverMetric(ctx context.Context, riverMetric rivertype.Metric) {
+	metricName := string(riverMetric.Name())
+
+	value := reflect.ValueOf(riverMetric)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+
+	layout := m.riverMetricLayout(metricName, value.Type())
+	if layout == nil || value.Kind() != reflect.Struct {
+		m.metrics.metricEmitUnmappedCount.Add(ctx, 1,
+			m.metricAttributes(attribute.String("metric_name", metricName)))
+		return
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(layout.attributeFields))
+	for _, field := range layout.attributeFields {
+		fieldValue := value.Field(field.index)
+		if fieldValue.Kind() == reflect.Bool {
+			attrs = append(attrs, attribute.Bool(field.key, fieldValue.Bool()))
+		} else {
+			attrs = append(attrs, attribute.String(field.key, fieldValue.String()))
+		}
+	}
+	measurementOpt := m.metricAttributes(attrs...)
+
+	for _, field := range layout.valueFields {
+		fieldValue := value.Field(field.index)
+
+		switch field.kind {
+		case riverMetricFieldKindDuration:
+			m.riverMetricFloat64Histogram(field.instrumentName, true).Record(ctx,
+				m.durationInPreferredUnit(time.Duration(fieldValue.Int())), measurementOpt)
+		case riverMetricFieldKindFloat:
+			m.riverMetricFloat64Histogram(field.instrumentName, false).Record(ctx, fieldValue.Float(), measurementOpt)
+		case riverMetricFieldKindInt:
+			var intValue int64
+			if fieldValue.CanInt() {
+				intValue = fieldValue.Int()
+			} else {
+				intValue = int64(min(fieldValue.Uint(), math.MaxInt64))
+			}
+			m.riverMetricInt64Histogram(field.instrumentName).Record(ctx, intValue, measurementOpt)
+		case riverMetricFieldKindAttribute:
+		}
+	}
+}
+
+// riverMetricFloat64Histogram returns a histogram for a River metric, creating
+// it the first time the metric is seen. Unlike instruments created in
+// NewMiddleware, errors creating it are sent to OpenTelemetry's error handler
+// instead of panicking because this runs on River's goroutines. The SDK still
+// returns a usable instrument alongside errors like an invalid name.
+func (m *Middleware) riverMetricFloat64Histogram(instrumentName string, isDuration bool) metric.Float64Histogram {
+	if histogram, ok := m.riverMetrics.float64Histograms.Load(instrumentName); ok {
+		return histogram.(metric.Float64Histogram) //nolint:forcetypeassert
+	}
+
+	description, unit := riverMetricInstrumentDescription(instrumentName)
+	if isDuration {
+		unit = cmp.Or(m.config.DurationUnit, "s")
+	}
+
+	options := []metric.Float64HistogramOption{metric.WithDescription(description), metric.WithUnit(unit)}
+	if boundaries, ok := m.config.HistogramBucketBoundaries[instrumentName]; ok {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+
+	newHistogram, err := m.meter.Float64Histogram(instrumentName, options...)
+	if err != nil {
+		otel.Handle(err)
+		if newHistogram == nil {
+			newHistogram = noop.Float64Histogram{}
+		}
+	}
+
+	histogram, _ := m.riverMetrics.float64Histograms.LoadOrStore(instrumentName, newHistogram)
+	return histogram.(metric.Float64Histogram) //nolint:forcetypeassert
+}
+
+// riverMetricInt64Histogram is like riverMetricFloat64Histogram, but for
+// integer values.
+func (m *Middleware) riverMetricInt64Histogram(instrumentName string) metric.Int64Histogram {
+	if histogram, ok := m.riverMetrics.int64Histograms.Load(instrumentName); ok {
+		return histogram.(metric.Int64Histogram) //nolint:forcetypeassert
+	}
+
+	description, unit := riverMetricInstrumentDescription(instrumentName)
+
+	options := []metric.Int64HistogramOption{metric.WithDescription(description), metric.WithUnit(unit)}
+	if boundaries, ok := m.config.HistogramBucketBoundaries[instrumentName]; ok {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+
+	newHistogram, err := m.meter.Int64Histogram(instrumentName, options...)
+	if err != nil {
+		otel.Handle(err)
+		if newHistogram == nil {
+			newHistogram = noop.Int64Histogram{}
+		}
+	}
+
+	histogram, _ := m.riverMetrics.int64Histograms.LoadOrStore(instrumentName, newHistogram)
+	return histogram.(metric.Int64Histogram) //nolint:forcetypeassert
+}
+
+// riverMetricLayout returns how the given River metric type maps to
+// instruments, or nil if it can't be mapped.
+func (m *Middleware) riverMetricLayout(metricName string, metricType reflect.Type) *riverMetricLayout {
+	if layout, ok := m.riverMetrics.layouts.Load(metricType); ok {
+		return layout.(*riverMetricLayout) //nolint:forcetypeassert
+	}
+
+	layout, _ := m.riverMetrics.layouts.LoadOrStore(metricType, buildRiverMetricLayout(metricName, metricType))
+	return layout.(*riverMetricLayout) //nolint:forcetypeassert
+}
+
+func buildRiverMetricLayout(metricName string, metricType reflect.Type) *riverMetricLayout {
+	if metricType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	durationType := reflect.TypeFor[time.Duration]()
+
+	layout := &riverMetricLayout{}
+	for i := range metricType.NumField() {
+		structField := metricType.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		field := riverMetricField{index: i, key: snakeCase(structField.Name)}
+
+		switch kind := structField.Type.Kind(); {
+		case structField.Type == durationType:
+			field.kind = riverMetricFieldKindDuration
+		case kind == reflect.Bool || kind == reflect.String:
+			field.kind = riverMetricFieldKindAttribute
+			layout.attributeFields = append(layout.attributeFields, field)
+			continue
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			field.kind = riverMetricFieldKindFloat
+		case kind >= reflect.Int && kind <= reflect.Uint64:
+			field.kind = riverMetricFieldKindInt
+		default:
+			continue
+		}
+
+		layout.valueFields = append(layout.valueFields, field)
+	}
+
+	if len(layout.valueFields) < 1 {
+		return nil
+	}
+
+	for i := range layout.valueFields {
+		layout.valueFields[i].instrumentName = prefix + metricName
+		if len(layout.valueFields) > 1 {
+			layout.valueFields[i].instrumentName += "_" + layout.valueFields[i].key
+		}
+	}
+
+	return layout
+}
+
+func riverMetricInstrumentDescription(instrumentName string) (string, string) {
+	if info, ok := riverMetricInstrumentInfos[instrumentName]; ok {
+		return info.description, info.unit
+	}
+	return "Metric emitted by River as " + strings.TrimPrefix(instrumentName, prefix), ""
+}
+
+// snakeCase converts a Go field name like "JobCount" or "QueueID" to snake
+// case like "job_count" or "queue_id".
+func snakeCase(s string) string {
+	runes := []rune(s)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at an upper case letter following a lower case
+			// one, or at the last upper case letter of an acronym followed by
+			// a lower case one, like the "P" in "HTTPServer".
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,17 @@
+package otelriver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnakeCase(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "count", snakeCase("Count"))
+	require.Equal(t, "job_count", snakeCase("JobCount"))
+	require.Equal(t, "queue_id", snakeCase("QueueID"))
+	require.Equal(t, "http_server", snakeCase("HTTPServer"))
+	require.Equal(t, "num_http_requests", snakeCase("NumHTTPRequests"))
+}
@@ -0,0 +1,187 @@
+package versionedjob
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/riverqueue/river/rivertype"
+)
+
+// Verify interface compliance.
+var (
+	_ MetadataVersionTransformer = &StepTransformer{}
+	_ VersionStamper             = &StepTransformer{}
+	_ VersionTransformer         = &StepTransformer{}
+)
+
+// Step is a single version change for a StepTransformer, upgrading a job's
+// encoded args from the version before Version to Version.
+type Step struct {
+	// Transform upgrades encoded args from the previous version to Version,
+	// returning the modified args. It doesn't need to update the version in
+	// args because StepTransformer takes care of that once all steps have been
+	// applied.
+	Transform func(args []byte) ([]byte, error)
+
+	// Version is the version that this step upgrades args to. The first step
+	// should upgrade to version 2 because jobs without a version are assumed to
+	// be version 1, and each successive step should upgrade to the version
+	// after the one before it.
+	Version int
+}
+
+// StepTransformerConfig is configuration for a StepTransformer.
+type StepTransformerConfig struct {
+	// Kind is the job kind that the transformer applies to.
+	Kind string
+
+	// Steps are version changes applied in order to bring a job's args from
+	// the version they were encoded as up to the latest version, which is the
+	// version of the last step.
+	Steps []Step
+
+	// VersionPath is the path in job args where a job's version is stored, in
+	// gjson/sjson syntax. Unused when the hook tracks versions in metadata
+	// with HookConfig.MetadataVersionPath.
+	//
+	// Defaults to "version".
+	VersionPath string
+}
+
+// StepTransformer is a VersionTransformer that upgrades jobs by applying a
+// series of registered steps, each one changing a job's args from one version
+// to the next. It takes care of extracting a job's version from its args,
+// applying only the steps needed to bring it to the latest version, and
+// storing the latest version back to args.
+//
+// Jobs from a version newer than the latest known version, like ones inserted
+// by a newer deploy of a program and picked up by an older one still running,
+// are returned an error so they're retried instead of worked incorrectly.
+type StepTransformer struct {
+	config *StepTransformerConfig
+}
+
+// NewStepTransformer initializes a new StepTransformer. Panics if config is nil
+// or has no kind, or if its steps aren't ordered by version.
+func NewStepTransformer(config *StepTransformerConfig) *StepTransformer {
+	if config == nil {
+		config = &StepTransformerConfig{}
+	}
+
+	if config.Kind == "" {
+		panic("step transformer must have a kind")
+	}
+
+	for i, step := range config.Steps {
+		if step.Transform == nil {
+			panic(fmt.Sprintf("step transformer for kind %q has step for version %d without a transform", config.Kind, step.Version))
+		}
+		if step.Version != i+2 {
+			panic(fmt.Sprintf("step transformer for kind %q should have step %d for version %d, but it was for version %d", config.Kind, i, i+2, step.Version))
+		}
+	}
+
+	return &StepTransformer{
+		config: &StepTransformerConfig{
+			Kind:        config.Kind,
+			Steps:       config.Steps,
+			VersionPath: cmp.Or(config.VersionPath, "version"),
+		},
+	}
+}
+
+func (t *StepTransformer) Kind() string { return t.config.Kind }
+
+// Version is the latest version of the transformer's job kind, which is the
+// version of its last step, or 1 if it has no steps.
+func (t *StepTransformer) Version() int { return len(t.config.Steps) + 1 }
+
+// VersionStamp stores the latest version in the args of a job being inserted
+// if they don't already have a version. A version of zero, like from an args
+// struct whose version field was left unset, is treated as no version.
+func (t *StepTransformer) VersionStamp(ctx context.Context, params *rivertype.JobInsertParams) error {
+	if versionRes := gjson.GetBytes(params.EncodedArgs, t.config.VersionPath); versionRes.Exists() &&
+		(versionRes.Type != gjson.Number || versionRes.Num >= 1) {
+		return nil
+	}
+
+	encodedArgs, err := sjson.SetBytes(params.EncodedArgs, t.config.VersionPath, t.Version())
+	if err != nil {
+		return fmt.Errorf("error setting job version: %w", err)
+	}
+
+	params.EncodedArgs = encodedArgs
+	return nil
+}
+
+func (t *StepTransformer) VersionTransform(ctx context.Context, job *rivertype.JobRow) error {
+	version := 1
+	if versionRes := gjson.GetBytes(job.EncodedArgs, t.config.VersionPath); versionRes.Exists() {
+		parsedVersion, err := strconv.Atoi(versionRes.Raw)
+		if err != nil || parsedVersion < 0 {
+			return fmt.Errorf("job args have invalid version at path %q: %s", t.config.VersionPath, versionRes.Raw)
+		}
+
+		// Already the latest version; nothing to do.
+		if parsedVersion == t.Version() {
+			return nil
+		}
+
+		// A zero version, like from an args struct whose version field was
+		// never set, is treated the same as no version at all.
+		version = max(parsedVersion, 1)
+	}
+
+	if version > t.Version() {
+		return fmt.Errorf("job version %d is newer than latest known version %d for kind %q", version, t.Version(), t.config.Kind)
+	}
+
+	encodedArgs, err := t.applySteps(job.EncodedArgs, version)
+	if err != nil {
+		return err
+	}
+
+	encodedArgs, err = sjson.SetBytes(encodedArgs, t.config.VersionPath, t.Version())
+	if err != nil {
+		return fmt.Errorf("error setting job version: %w", err)
+	}
+
+	job.EncodedArgs = encodedArgs
+	return nil
+}
+
+// VersionTransformFrom applies the steps needed to bring the given job's args
+// from version up to the latest version, without reading or writing a version
+// in args. It's used when the hook tracks versions in job metadata.
+func (t *StepTransformer) VersionTransformFrom(ctx context.Context, job *rivertype.JobRow, version int) error {
+	if version < 1 || version > t.Version() {
+		return fmt.Errorf("job version %d is out of range of known versions 1 to %d for kind %q", version, t.Version(), t.config.Kind)
+	}
+
+	encodedArgs, err := t.applySteps(job.EncodedArgs, version)
+	if err != nil {
+		return err
+	}
+
+	job.EncodedArgs = encodedArgs
+	return nil
+}
+
+// applySteps applies steps to encoded args of the given version to bring them
+// up to the latest version.
+func (t *StepTransformer) applySteps(encodedArgs []byte, version int) ([]byte, error) {
+	for _, step := range t.config.Steps[version-1:] {
+		var err error
+		encodedArgs, err = step.Transform(encodedArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error transforming job of kind %q to version %d: %w", t.config.Kind, step.Version, err)
+		}
+	}
+
+	return encodedArgs, nil
+}
@@ -0,0 +1,328 @@
+package versionedjob_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/riverqueue/river/rivertype"
+	"github.com/riverqueue/rivercontrib/versionedjob"
+)
+
+func TestStepTransformer(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	type testBundle struct {
+		stepsRun []int
+	}
+
+	setupConfig := func(t *testing.T, config *versionedjob.StepTransformerConfig) (*versionedjob.StepTransformer, *testBundle) {
+		t.Helper()
+
+		bundle := &testBundle{}
+
+		config.Kind = (VersionedJobArgs{}).Kind()
+		config.Steps = []versionedjob.Step{
+			{
+				Transform: func(args []byte) ([]byte, error) {
+					bundle.stepsRun = append(bundle.stepsRun, 2)
+
+					args, err := sjson.SetBytes(args, "title", gjson.GetBytes(args, "name").String())
+					if err != nil {
+						return nil, err
+					}
+					return sjson.DeleteBytes(args, "name")
+				},
+				Version: 2,
+			},
+			{
+				Transform: func(args []byte) ([]byte, error) {
+					bundle.stepsRun = append(bundle.stepsRun, 3)
+
+					title := gjson.GetBytes(args, "title").String()
+					if title == "" {
+						return nil, errors.New("no title found in job args")
+					}
+					return sjson.SetBytes(args, "description", "A description of a "+title+".")
+				},
+				Version: 3,
+			},
+		}
+
+		return versionedjob.NewStepTransformer(config), bundle
+	}
+
+	setup := func(t *testing.T) (*versionedjob.StepTransformer, *testBundle) {
+		t.Helper()
+
+		return setupConfig(t, &versionedjob.StepTransformerConfig{})
+	}
+
+	t.Run("CurrentVersionNoOp", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		encodedArgs := []byte(`{"title":"My Job","description":"A description of a My Job.","version":3}`)
+		job := &rivertype.JobRow{EncodedArgs: encodedArgs, Kind: transformer.Kind()}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+		require.Empty(t, bundle.stepsRun)
+	})
+
+	t.Run("AppliesVersion", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, map[string]any{
+				"title":   "My Job",
+				"version": 2,
+			}),
+			Kind: transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
+		require.Equal(t, []int{3}, bundle.stepsRun)
+	})
+
+	t.Run("AppliesMultipleVersions", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, map[string]any{
+				"name": "My Job",
+			}),
+			Kind: transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
+		require.Equal(t, []int{2, 3}, bundle.stepsRun)
+	})
+
+	t.Run("ZeroVersion", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		job := &rivertype.JobRow{EncodedArgs: []byte(`{"name":"My Job","version":0}`), Kind: transformer.Kind()}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
+		require.Equal(t, []int{2, 3}, bundle.stepsRun)
+	})
+
+	t.Run("VersionPath", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setupConfig(t, &versionedjob.StepTransformerConfig{
+			VersionPath: "meta.schema_version",
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, map[string]any{
+				"meta":  map[string]any{"schema_version": 2},
+				"title": "My Job",
+			}),
+			Kind: transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, int64(3), gjson.GetBytes(job.EncodedArgs, "meta.schema_version").Int())
+		require.Equal(t, "A description of a My Job.", gjson.GetBytes(job.EncodedArgs, "description").String())
+	})
+
+	t.Run("NewerVersionError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		encodedArgs := []byte(`{"title":"My Job","version":4}`)
+		job := &rivertype.JobRow{EncodedArgs: encodedArgs, Kind: transformer.Kind()}
+
+		require.EqualError(t, transformer.VersionTransform(ctx, job),
+			`job version 4 is newer than latest known version 3 for kind "versioned_job"`)
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+		require.Empty(t, bundle.stepsRun)
+	})
+
+	t.Run("InvalidVersionError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{EncodedArgs: []byte(`{"title":"My Job","version":"two"}`), Kind: transformer.Kind()}
+
+		require.EqualError(t, transformer.VersionTransform(ctx, job),
+			`job args have invalid version at path "version": "two"`)
+
+		job = &rivertype.JobRow{EncodedArgs: []byte(`{"title":"My Job","version":-1}`), Kind: transformer.Kind()}
+
+		require.EqualError(t, transformer.VersionTransform(ctx, job),
+			`job args have invalid version at path "version": -1`)
+	})
+
+	t.Run("StepError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		encodedArgs := []byte(`{"version":2}`)
+		job := &rivertype.JobRow{EncodedArgs: encodedArgs, Kind: transformer.Kind()}
+
+		require.EqualError(t, transformer.VersionTransform(ctx, job),
+			`error transforming job of kind "versioned_job" to version 3: no title found in job args`)
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+		require.Equal(t, []int{3}, bundle.stepsRun)
+	})
+
+	t.Run("NoSteps", func(t *testing.T) {
+		t.Parallel()
+
+		transformer := versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{Kind: "no_steps"})
+		require.Equal(t, 1, transformer.Version())
+
+		job := &rivertype.JobRow{EncodedArgs: []byte(`{}`), Kind: transformer.Kind()}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.JSONEq(t, `{"version":1}`, string(job.EncodedArgs))
+	})
+
+	t.Run("VersionTransformFrom", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		job := &rivertype.JobRow{EncodedArgs: []byte(`{"name":"My Job"}`), Kind: transformer.Kind()}
+
+		require.NoError(t, transformer.VersionTransformFrom(ctx, job, 1))
+		require.JSONEq(t, `{"title":"My Job","description":"A description of a My Job."}`, string(job.EncodedArgs))
+		require.Equal(t, []int{2, 3}, bundle.stepsRun)
+	})
+
+	t.Run("VersionTransformFromOutOfRangeError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, bundle := setup(t)
+
+		job := &rivertype.JobRow{EncodedArgs: []byte(`{"title":"My Job"}`), Kind: transformer.Kind()}
+
+		require.EqualError(t, transformer.VersionTransformFrom(ctx, job, 4),
+			`job version 4 is out of range of known versions 1 to 3 for kind "versioned_job"`)
+		require.Empty(t, bundle.stepsRun)
+	})
+
+	t.Run("VersionStamp", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		params := &rivertype.JobInsertParams{EncodedArgs: []byte(`{"title":"My Job"}`), Kind: transformer.Kind()}
+		require.NoError(t, transformer.VersionStamp(ctx, params))
+		require.JSONEq(t, `{"title":"My Job","version":3}`, string(params.EncodedArgs))
+	})
+
+	t.Run("VersionStampExistingVersion", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		encodedArgs := []byte(`{"name":"My Job","version":1}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: transformer.Kind()}
+		require.NoError(t, transformer.VersionStamp(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+	})
+
+	t.Run("VersionStampZeroVersion", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		params := &rivertype.JobInsertParams{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgs{Title: "My Job"}),
+			Kind:        transformer.Kind(),
+		}
+		require.NoError(t, transformer.VersionStamp(ctx, params))
+		require.Equal(t, 3, mustUnmarshalJSON[VersionedJobArgs](t, params.EncodedArgs).Version)
+	})
+
+	t.Run("VersionStampVersionPath", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setupConfig(t, &versionedjob.StepTransformerConfig{
+			VersionPath: "meta.schema_version",
+		})
+
+		params := &rivertype.JobInsertParams{EncodedArgs: []byte(`{"title":"My Job"}`), Kind: transformer.Kind()}
+		require.NoError(t, transformer.VersionStamp(ctx, params))
+		require.JSONEq(t, `{"meta":{"schema_version":3},"title":"My Job"}`, string(params.EncodedArgs))
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+		require.Equal(t, 3, transformer.Version())
+	})
+
+	t.Run("PanicsOnMissingKind", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "step transformer must have a kind", func() {
+			versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{})
+		})
+		require.PanicsWithValue(t, "step transformer must have a kind", func() {
+			versionedjob.NewStepTransformer(nil)
+		})
+	})
+
+	t.Run("PanicsOnMissingTransform", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `step transformer for kind "versioned_job" has step for version 2 without a transform`, func() {
+			versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{
+				Kind:  "versioned_job",
+				Steps: []versionedjob.Step{{Version: 2}},
+			})
+		})
+	})
+
+	t.Run("PanicsOnOutOfOrderSteps", func(t *testing.T) {
+		t.Parallel()
+
+		transform := func(args []byte) ([]byte, error) { return args, nil }
+
+		require.PanicsWithValue(t, `step transformer for kind "versioned_job" should have step 1 for version 3, but it was for version 4`, func() {
+			versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{
+				Kind: "versioned_job",
+				Steps: []versionedjob.Step{
+					{Transform: transform, Version: 2},
+					{Transform: transform, Version: 4},
+				},
+			})
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package versionedjob
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// TypedTransformerConfig is configuration for a TypedTransformer.
+type TypedTransformerConfig struct {
+	// Kind is the job kind that the transformer applies to.
+	//
+	// Defaults to the kind of the args type that the transformer's first
+	// step produces if it implements Kind like job args do.
+	Kind string
+
+	// VersionPath is the path in job args where a job's version is stored, in
+	// gjson/sjson syntax. Unused when the hook tracks versions in metadata
+	// with HookConfig.MetadataVersionPath.
+	//
+	// Defaults to "version".
+	VersionPath string
+}
+
+// TypedTransformer is a StepTransformer whose steps are Go functions that
+// convert one version of an args struct to the next, with args unmarshaled
+// and marshaled by the transformer. Steps are compile-checked because each one
+// must accept the args type that the step before it returned, and they can be
+// unit tested as plain functions. Args is the latest version's args type.
+//
+// Initialize one with NewTypedTransformer and add more steps with AddTypedStep.
+// Because args are round tripped through structs, fields that an args struct
+// doesn't define are dropped.
+type TypedTransformer[Args any] struct {
+	*StepTransformer
+}
+
+// NewTypedTransformer initializes a new TypedTransformer with a single step
+// that converts version 1 args of type Old to version 2 args of type New.
+//
+// config may be nil.
+func NewTypedTransformer[Old, New any](config *TypedTransformerConfig, transform func(args Old) (New, error)) *TypedTransformer[New] {
+	if config == nil {
+		config = &TypedTransformerConfig{}
+	}
+
+	kind := config.Kind
+	if kind == "" {
+		var newArgs New
+		if argsWithKind, ok := any(newArgs).(interface{ Kind() string }); ok {
+			kind = argsWithKind.Kind()
+		}
+	}
+
+	return &TypedTransformer[New]{
+		StepTransformer: NewStepTransformer(&StepTransformerConfig{
+			Kind:        kind,
+			Steps:       []Step{typedStep(2, transform)},
+			VersionPath: config.VersionPath,
+		}),
+	}
+}
+
+// AddTypedStep returns a new TypedTransformer with all the steps of the given
+// transformer plus one more that converts its latest args of type Prev to a
+// new latest version of type Next. The given transformer is left unchanged.
+func AddTypedStep[Prev, Next any](transformer *TypedTransformer[Prev], transform func(args Prev) (Next, error)) *TypedTransformer[Next] {
+	config := transformer.config
+
+	return &TypedTransformer[Next]{
+		StepTransformer: NewStepTransformer(&StepTransformerConfig{
+			Kind:        config.Kind,
+			Steps:       append(slices.Clone(config.Steps), typedStep(transformer.Version()+1, transform)),
+			VersionPath: config.VersionPath,
+		}),
+	}
+}
+
+// typedStep returns a step that unmarshals args as From, converts them with
+// transform, and marshals the result.
+func typedStep[From, To any](version int, transform func(args From) (To, error)) Step {
+	return Step{
+		Transform: func(encodedArgs []byte) ([]byte, error) {
+			var args From
+			if err := json.Unmarshal(encodedArgs, &args); err != nil {
+				return nil, fmt.Errorf("error unmarshaling args: %w", err)
+			}
+
+			newArgs, err := transform(args)
+			if err != nil {
+				return nil, err
+			}
+
+			newEncodedArgs, err := json.Marshal(newArgs)
+			if err != nil {
+				return nil, fmt.Errorf("error marshaling args: %w", err)
+			}
+
+			return newEncodedArgs, nil
+		},
+		Version: version,
+	}
+}
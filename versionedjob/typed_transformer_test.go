@@ -0,0 +1,180 @@
+package versionedjob_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/river/rivertype"
+	"github.com/riverqueue/rivercontrib/versionedjob"
+)
+
+func TestTypedTransformer(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	type testBundle struct{}
+
+	setupConfig := func(t *testing.T, config *versionedjob.TypedTransformerConfig) (*versionedjob.TypedTransformer[VersionedJobArgs], *testBundle) {
+		t.Helper()
+
+		return versionedjob.AddTypedStep(
+			versionedjob.NewTypedTransformer(config, transformVersionedJobV1ToV2),
+			transformVersionedJobV2ToV3,
+		), &testBundle{}
+	}
+
+	setup := func(t *testing.T) (*versionedjob.TypedTransformer[VersionedJobArgs], *testBundle) {
+		t.Helper()
+
+		return setupConfig(t, nil)
+	}
+
+	t.Run("CurrentVersionNoOp", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		encodedArgs := []byte(`{"title":"My Job","description":"A description of a My Job.","version":3}`)
+		job := &rivertype.JobRow{EncodedArgs: encodedArgs, Kind: transformer.Kind()}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+	})
+
+	t.Run("AppliesVersion", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgsV2{Title: "My Job", Version: 2}),
+			Kind:        transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
+	})
+
+	t.Run("AppliesMultipleVersions", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgsV1{Name: "My Job"}),
+			Kind:        transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
+	})
+
+	t.Run("StepError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgsV2{Version: 2}),
+			Kind:        transformer.Kind(),
+		}
+
+		require.EqualError(t, transformer.VersionTransform(ctx, job),
+			`error transforming job of kind "versioned_job" to version 3: no title found in job args`)
+	})
+
+	t.Run("UnmarshalError", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"name":123}`),
+			Kind:        transformer.Kind(),
+		}
+
+		require.ErrorContains(t, transformer.VersionTransform(ctx, job),
+			`error transforming job of kind "versioned_job" to version 2: error unmarshaling args: `)
+	})
+
+	t.Run("KindFromArgs", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+		require.Equal(t, (VersionedJobArgs{}).Kind(), transformer.Kind())
+	})
+
+	t.Run("Kind", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setupConfig(t, &versionedjob.TypedTransformerConfig{Kind: "other_job"})
+		require.Equal(t, "other_job", transformer.Kind())
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+		require.Equal(t, 3, transformer.Version())
+	})
+
+	t.Run("AddTypedStepLeavesOriginalUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		transformerV2 := versionedjob.NewTypedTransformer(nil, transformVersionedJobV1ToV2)
+		transformerV3 := versionedjob.AddTypedStep(transformerV2, transformVersionedJobV2ToV3)
+		require.Equal(t, 2, transformerV2.Version())
+		require.Equal(t, 3, transformerV3.Version())
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgsV1{Name: "My Job"}),
+			Kind:        transformerV2.Kind(),
+		}
+
+		require.NoError(t, transformerV2.VersionTransform(ctx, job))
+		require.Equal(t, VersionedJobArgsV2{
+			Title:   "My Job",
+			Version: 2,
+		}, mustUnmarshalJSON[VersionedJobArgsV2](t, job.EncodedArgs))
+	})
+
+	t.Run("VersionTransformFrom", func(t *testing.T) {
+		t.Parallel()
+
+		transformer, _ := setup(t)
+
+		job := &rivertype.JobRow{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgsV1{Name: "My Job"}),
+			Kind:        transformer.Kind(),
+		}
+
+		require.NoError(t, transformer.VersionTransformFrom(ctx, job, 1))
+		require.JSONEq(t, `{"title":"My Job","description":"A description of a My Job.","version":0}`, string(job.EncodedArgs))
+	})
+}
+
+func transformVersionedJobV1ToV2(args VersionedJobArgsV1) (VersionedJobArgsV2, error) {
+	return VersionedJobArgsV2{Title: args.Name}, nil
+}
+
+func transformVersionedJobV2ToV3(args VersionedJobArgsV2) (VersionedJobArgs, error) {
+	if args.Title == "" {
+		return VersionedJobArgs{}, errors.New("no title found in job args")
+	}
+
+	return VersionedJobArgs{
+		Description: "A description of a " + args.Title + ".",
+		Title:       args.Title,
+	}, nil
+}
@@ -1,9 +1,20 @@
 package versionedjob
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/riverqueue/river/riverdriver"
 	"github.com/riverqueue/river/rivershared/baseservice"
+	"github.com/riverqueue/river/rivershared/util/dbutil"
 	"github.com/riverqueue/river/rivertype"
 )
 
@@ -23,11 +34,104 @@ type VersionTransformer interface {
 	VersionTransform(ctx context.Context, job *rivertype.JobRow) error
 }
 
+// MetadataVersionTransformer is a VersionTransformer for use with
+// HookConfig.MetadataVersionPath, where the hook tracks a job's version in its
+// metadata instead of its args. The hook extracts a job's version and passes
+// it to the transformer so that args don't need a version field, keeping args
+// structs focused on the domain. StepTransformer implements
+// MetadataVersionTransformer.
+type MetadataVersionTransformer interface {
+	VersionTransformer
+
+	// Version is the latest version of the transformer's job kind.
+	Version() int
+
+	// VersionTransformFrom applies version transformations to bring the given
+	// job's args from version up to the latest version. It's only invoked for
+	// jobs that aren't already the latest version.
+	VersionTransformFrom(ctx context.Context, job *rivertype.JobRow, version int) error
+}
+
+// VersionStamper may optionally be implemented by a VersionTransformer so that
+// jobs of its kind have their version stamped as they're inserted when
+// HookConfig.EnableInsertVersionStamping is set. This way, code inserting jobs
+// doesn't have to remember to set a version, and new jobs can't be mistaken
+// for an older version. StepTransformer implements VersionStamper.
+type VersionStamper interface {
+	// VersionStamp stores the latest version in a job being inserted if it
+	// doesn't already have a version.
+	VersionStamp(ctx context.Context, params *rivertype.JobInsertParams) error
+}
+
+// Driver is the subset of a River driver used by the hook to persist
+// transformed args with HookConfig.EnableArgsPersistence. River drivers like
+// the one returned by riverpgxv5.New satisfy it.
+type Driver interface {
+	DatabaseName() string
+	GetExecutor() riverdriver.Executor
+}
+
 // Verify interface compliance.
-var _ rivertype.HookWorkBegin = &Hook{}
+var (
+	_ rivertype.HookInsertBegin = &Hook{}
+	_ rivertype.HookWorkBegin   = &Hook{}
+)
 
 // HookConfig is configuration for the versionedjob hook.
 type HookConfig struct {
+	// Driver is the River driver used to persist transformed args with
+	// EnableArgsPersistence. It should be the same driver used to initialize
+	// the River client. Only Postgres drivers are supported.
+	Driver Driver
+
+	// EnableArgsPersistence causes the hook to write transformed args back to
+	// jobs in the database once they've been transformed successfully, so
+	// transformations aren't repeated when jobs are retried, and jobs
+	// inspected in the database or River UI reflect their latest version.
+	// With MetadataVersionPath, the latest version is written back to
+	// metadata as well. Only the top-level metadata key containing the version
+	// is updated so that changes to other keys made while the job was being
+	// worked aren't overwritten.
+	//
+	// Failing to persist args is logged, but doesn't fail a job because its
+	// transformed args can still be worked.
+	//
+	// Requires Driver.
+	EnableArgsPersistence bool
+
+	// EnableInsertVersionStamping causes the hook to stamp the latest version
+	// into jobs as they're inserted for kinds with a transformer that
+	// implements VersionStamper. Jobs inserted with an explicit version are
+	// left as is, so older versions can still be inserted deliberately. A
+	// version of zero in args counts as no version since it's what an args
+	// struct marshals when its version field is left unset.
+	//
+	// With MetadataVersionPath, versions are stamped into metadata instead for
+	// all kinds with a transformer.
+	EnableInsertVersionStamping bool
+
+	// MetadataVersionPath is a path in job metadata, in gjson/sjson syntax,
+	// where the hook tracks job versions instead of leaving it to transformers
+	// to track them in args. Jobs without a version are assumed to be version
+	// 1. Jobs are passed to their transformer along with their version, and
+	// their version is set to the latest version once they've been
+	// transformed. Jobs from a version newer than the latest version known by
+	// their transformer are errored so they're retried.
+	//
+	// Requires EnableInsertVersionStamping. Args don't carry a version that
+	// producers could set, so without stamping, newly inserted jobs whose args
+	// are already in the latest shape would be indistinguishable from version
+	// 1 jobs and have every step run on them. The hook must be installed on
+	// clients inserting jobs as well as those working them.
+	//
+	// When set, all transformers must implement MetadataVersionTransformer.
+	MetadataVersionPath string
+
+	// Schema is the database schema containing River's tables, used when
+	// persisting transformed args with EnableArgsPersistence. May be left
+	// empty to use the default search path.
+	Schema string
+
 	// Transformers are version transformers that the hook will apply. Only one
 	// version transformer should be registered for any particular job kind.
 	Transformers []VersionTransformer
@@ -40,8 +144,11 @@ type Hook struct {
 	baseservice.BaseService
 	rivertype.Hook
 
-	config          *HookConfig
-	transformersMap map[string]VersionTransformer
+	config             *HookConfig
+	exec               riverdriver.Executor // nil unless EnableArgsPersistence is set
+	metadataVersionKey string               // top-level metadata key containing the version with MetadataVersionPath
+	persistSQL         string
+	transformersMap    map[string]VersionTransformer
 }
 
 // NewHook initializes a new River versionedjob hook.
@@ -52,25 +159,191 @@ func NewHook(config *HookConfig) *Hook {
 		config = &HookConfig{}
 	}
 
+	if config.MetadataVersionPath != "" && !config.EnableInsertVersionStamping {
+		panic("EnableInsertVersionStamping is required with MetadataVersionPath")
+	}
+
 	transformersMap := make(map[string]VersionTransformer, len(config.Transformers))
 	for _, transformer := range config.Transformers {
 		if _, ok := transformersMap[transformer.Kind()]; ok {
 			panic("duplicate version transformer for kind: " + transformer.Kind())
 		}
 
+		if config.MetadataVersionPath != "" {
+			if _, ok := transformer.(MetadataVersionTransformer); !ok {
+				panic("version transformer must implement MetadataVersionTransformer when using MetadataVersionPath: " + transformer.Kind())
+			}
+		}
+
 		transformersMap[transformer.Kind()] = transformer
 	}
 
-	return &Hook{
+	hook := &Hook{
 		config:          config,
 		transformersMap: transformersMap,
 	}
+
+	if config.EnableArgsPersistence {
+		if config.Driver == nil {
+			panic("driver is required with EnableArgsPersistence")
+		}
+		if config.Driver.DatabaseName() != "postgres" {
+			panic("EnableArgsPersistence is only supported for Postgres drivers")
+		}
+
+		hook.exec = config.Driver.GetExecutor()
+		hook.metadataVersionKey = topLevelKey(config.MetadataVersionPath)
+		hook.persistSQL = persistSQL(config.Schema, config.MetadataVersionPath != "")
+	}
+
+	return hook
+}
+
+func (h *Hook) InsertBegin(ctx context.Context, params *rivertype.JobInsertParams) error {
+	if !h.config.EnableInsertVersionStamping {
+		return nil
+	}
+
+	if h.config.MetadataVersionPath != "" {
+		transformer, ok := h.transformersMap[params.Kind].(MetadataVersionTransformer)
+		if !ok || gjson.GetBytes(params.Metadata, h.config.MetadataVersionPath).Exists() {
+			return nil
+		}
+
+		metadata, err := sjson.SetBytes(params.Metadata, h.config.MetadataVersionPath, transformer.Version())
+		if err != nil {
+			return fmt.Errorf("error setting job version in metadata: %w", err)
+		}
+
+		params.Metadata = metadata
+		return nil
+	}
+
+	if stamper, ok := h.transformersMap[params.Kind].(VersionStamper); ok {
+		return stamper.VersionStamp(ctx, params)
+	}
+
+	return nil
 }
 
 func (h *Hook) WorkBegin(ctx context.Context, job *rivertype.JobRow) error {
-	if transformer, ok := h.transformersMap[job.Kind]; ok {
-		return transformer.VersionTransform(ctx, job)
+	transformer, ok := h.transformersMap[job.Kind]
+	if !ok {
+		return nil
+	}
+
+	var (
+		originalArgs     = job.EncodedArgs
+		originalMetadata = job.Metadata
+	)
+
+	if h.config.MetadataVersionPath != "" {
+		if err := h.versionTransformMetadata(ctx, transformer.(MetadataVersionTransformer), job); err != nil { //nolint:forcetypeassert
+			return err
+		}
+	} else {
+		if err := transformer.VersionTransform(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	// With MetadataVersionPath, a version bump may leave args unchanged, like
+	// for steps that only validate args, but it still needs to be persisted
+	// so the steps aren't run again on retries.
+	if h.exec != nil && (!bytes.Equal(originalArgs, job.EncodedArgs) || !bytes.Equal(originalMetadata, job.Metadata)) {
+		h.persistArgs(ctx, job)
+	}
+
+	return nil
+}
+
+// persistArgs writes a job's transformed args, and metadata if versions are
+// tracked in metadata, back to the database.
+func (h *Hook) persistArgs(ctx context.Context, job *rivertype.JobRow) {
+	if err := h.execPersistSQL(ctx, job); err != nil {
+		h.Logger.WarnContext(ctx, "versionedjob: Error persisting transformed job args",
+			slog.Int64("job_id", job.ID), slog.String("kind", job.Kind), slog.String("err", err.Error()))
+	}
+}
+
+func (h *Hook) execPersistSQL(ctx context.Context, job *rivertype.JobRow) error {
+	params := []any{job.ID, string(job.EncodedArgs)}
+	if h.config.MetadataVersionPath != "" {
+		var metadata map[string]json.RawMessage
+		if err := json.Unmarshal(job.Metadata, &metadata); err != nil {
+			return fmt.Errorf("error unmarshaling job metadata: %w", err)
+		}
+
+		params = append(params, h.metadataVersionKey, string(metadata[h.metadataVersionKey]))
+	}
+
+	return h.exec.Exec(ctx, h.persistSQL, params...)
+}
+
+// versionTransformMetadata transforms a job whose version is tracked in its
+// metadata at MetadataVersionPath.
+func (h *Hook) versionTransformMetadata(ctx context.Context, transformer MetadataVersionTransformer, job *rivertype.JobRow) error {
+	version := 1
+	if versionRes := gjson.GetBytes(job.Metadata, h.config.MetadataVersionPath); versionRes.Exists() {
+		var err error
+		version, err = strconv.Atoi(versionRes.Raw)
+		if err != nil || version < 1 {
+			return fmt.Errorf("job metadata has invalid version at path %q: %s", h.config.MetadataVersionPath, versionRes.Raw)
+		}
+	}
+
+	latestVersion := transformer.Version()
+	switch {
+	case version == latestVersion:
+		return nil
+	case version > latestVersion:
+		return fmt.Errorf("job version %d is newer than latest known version %d for kind %q", version, latestVersion, job.Kind)
+	}
+
+	if err := transformer.VersionTransformFrom(ctx, job, version); err != nil {
+		return err
+	}
+
+	metadata, err := sjson.SetBytes(job.Metadata, h.config.MetadataVersionPath, latestVersion)
+	if err != nil {
+		return fmt.Errorf("error setting job version in metadata: %w", err)
 	}
 
+	job.Metadata = metadata
 	return nil
 }
+
+// persistSQL produces a query that updates a job's args, and optionally its
+// metadata, by ID.
+func persistSQL(schema string, withMetadata bool) string {
+	table := "river_job"
+	if schema != "" {
+		table = dbutil.SafeIdentifier(schema) + "." + table
+	}
+
+	if withMetadata {
+		return "UPDATE " + table + " SET args = $2::jsonb, metadata = metadata || jsonb_build_object($3::text, $4::jsonb) WHERE id = $1"
+	}
+
+	return "UPDATE " + table + " SET args = $2::jsonb WHERE id = $1"
+}
+
+// topLevelKey returns the first key of a gjson/sjson path, like "river" for
+// "river.version".
+func topLevelKey(path string) string {
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			if i+1 < len(path) {
+				i++
+				sb.WriteByte(path[i])
+			}
+		case '.':
+			return sb.String()
+		default:
+			sb.WriteByte(path[i])
+		}
+	}
+	return sb.String()
+}
@@ -1,13 +1,23 @@
 package versionedjob_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
+	"github.com/riverqueue/river/riverdbtest"
+	"github.com/riverqueue/river/riverdriver"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
 	"github.com/riverqueue/river/rivershared/baseservice"
 	"github.com/riverqueue/river/rivershared/riversharedtest"
+	"github.com/riverqueue/river/rivershared/testfactory"
+	"github.com/riverqueue/river/rivershared/util/ptrutil"
 	"github.com/riverqueue/river/rivertype"
 	"github.com/riverqueue/rivercontrib/versionedjob"
 )
@@ -107,6 +117,672 @@ func TestHook(t *testing.T) {
 			Version:     3,
 		}, mustUnmarshalJSON[VersionedJobArgs](t, job.EncodedArgs))
 	})
+
+	t.Run("InsertBeginStampsVersion", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		params := &rivertype.JobInsertParams{EncodedArgs: []byte(`{"title":"My Job"}`), Kind: "versioned_job"}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.JSONEq(t, `{"title":"My Job","version":3}`, string(params.EncodedArgs))
+	})
+
+	t.Run("InsertBeginStampsZeroVersion", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		// Args inserted without setting Version marshal with a zero version,
+		// which gets stamped like a missing one.
+		params := &rivertype.JobInsertParams{
+			EncodedArgs: mustMarshalJSON(t, VersionedJobArgs{Title: "My Job"}),
+			Kind:        "versioned_job",
+		}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, VersionedJobArgs{Title: "My Job", Version: 3}, mustUnmarshalJSON[VersionedJobArgs](t, params.EncodedArgs))
+	})
+
+	t.Run("InsertBeginExplicitVersionUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job","version":2}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: "versioned_job"}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+	})
+
+	t.Run("InsertBeginStampingDisabled", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job"}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: "versioned_job"}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+	})
+
+	t.Run("InsertBeginUnregisteredKind", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job"}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: "other_job"}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+	})
+
+	t.Run("InsertBeginTransformerNotStamper", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			Transformers: []versionedjob.VersionTransformer{
+				&VersionedJobTransformer{},
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job"}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: (VersionedJobArgs{}).Kind()}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+	})
+
+	t.Run("MetadataVersion", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{"river:version":2}`),
+		}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.JSONEq(t, `{"title":"My Job","description":"A description of a My Job."}`, string(job.EncodedArgs))
+		require.JSONEq(t, `{"river:version":3}`, string(job.Metadata))
+	})
+
+	t.Run("MetadataVersionMissingIsV1", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"name":"My Job"}`),
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{}`),
+		}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.JSONEq(t, `{"title":"My Job","description":"A description of a My Job."}`, string(job.EncodedArgs))
+		require.JSONEq(t, `{"river:version":3}`, string(job.Metadata))
+	})
+
+	t.Run("MetadataVersionCurrentNoOp", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		var (
+			encodedArgs = []byte(`{"title":"My Job","description":"A description of a My Job."}`)
+			metadata    = []byte(`{"river:version":3}`)
+		)
+		job := &rivertype.JobRow{EncodedArgs: encodedArgs, Kind: (VersionedJobArgs{}).Kind(), Metadata: metadata}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+		require.Equal(t, metadata, job.Metadata)
+	})
+
+	t.Run("MetadataVersionNewerError", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{"river:version":4}`),
+		}
+
+		require.EqualError(t, hook.WorkBegin(ctx, job),
+			`job version 4 is newer than latest known version 3 for kind "versioned_job"`)
+	})
+
+	t.Run("MetadataVersionInvalidError", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{"river:version":0}`),
+		}
+
+		require.EqualError(t, hook.WorkBegin(ctx, job),
+			`job metadata has invalid version at path "river:version": 0`)
+	})
+
+	t.Run("MetadataVersionInsertBeginStampsVersion", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job"}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: encodedArgs, Kind: (VersionedJobArgs{}).Kind(), Metadata: []byte(`{"foo":"bar"}`)}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, encodedArgs, params.EncodedArgs)
+		require.JSONEq(t, `{"foo":"bar","river:version":3}`, string(params.Metadata))
+	})
+
+	t.Run("MetadataVersionInsertBeginExplicitVersionUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		metadata := []byte(`{"river:version":1}`)
+		params := &rivertype.JobInsertParams{EncodedArgs: []byte(`{"name":"My Job"}`), Kind: (VersionedJobArgs{}).Kind(), Metadata: metadata}
+		require.NoError(t, hook.InsertBegin(ctx, params))
+		require.Equal(t, metadata, params.Metadata)
+	})
+
+	t.Run("MetadataVersionPanicsOnTransformerWithoutVersion", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "version transformer must implement MetadataVersionTransformer when using MetadataVersionPath: versioned_job", func() {
+			versionedjob.NewHook(&versionedjob.HookConfig{
+				EnableInsertVersionStamping: true,
+				MetadataVersionPath:         "river:version",
+				Transformers: []versionedjob.VersionTransformer{
+					&VersionedJobTransformer{},
+				},
+			})
+		})
+	})
+
+	t.Run("MetadataVersionPanicsWithoutInsertVersionStamping", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "EnableInsertVersionStamping is required with MetadataVersionPath", func() {
+			versionedjob.NewHook(&versionedjob.HookConfig{
+				MetadataVersionPath: "river:version",
+				Transformers: []versionedjob.VersionTransformer{
+					newVersionedJobStepTransformer(),
+				},
+			})
+		})
+	})
+
+	t.Run("PersistArgs", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence: true,
+			Transformers: []versionedjob.VersionTransformer{
+				&VersionedJobTransformer{},
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job","version":2}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+		}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Len(t, exec.execCalls, 1)
+		require.Equal(t, "UPDATE river_job SET args = $2::jsonb WHERE id = $1", exec.execCalls[0].sql)
+		require.Equal(t, []any{int64(123), string(job.EncodedArgs)}, exec.execCalls[0].args)
+	})
+
+	t.Run("PersistArgsWithMetadataAndSchema", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                      &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence:       true,
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Schema:                      "custom_schema",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{"river:version":2}`),
+		}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Len(t, exec.execCalls, 1)
+		require.Equal(t, `UPDATE "custom_schema".river_job SET args = $2::jsonb, metadata = metadata || jsonb_build_object($3::text, $4::jsonb) WHERE id = $1`, exec.execCalls[0].sql)
+		require.Equal(t, []any{int64(123), string(job.EncodedArgs), "river:version", "3"}, exec.execCalls[0].args)
+	})
+
+	t.Run("PersistArgsWithNestedMetadataPath", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                      &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence:       true,
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "versioning.version",
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+			Metadata:    []byte(`{"other":"value","versioning":{"version":2}}`),
+		}
+
+		// Only the top-level key containing the version is written.
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Len(t, exec.execCalls, 1)
+		require.Equal(t, []any{int64(123), string(job.EncodedArgs), "versioning", `{"version":3}`}, exec.execCalls[0].args)
+	})
+
+	t.Run("PersistArgsMetadataVersionArgsUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                      &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence:       true,
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer("versioned_job", 3),
+			},
+		})
+
+		encodedArgs := []byte(`{"title":"My Job"}`)
+		job := &rivertype.JobRow{
+			EncodedArgs: encodedArgs,
+			ID:          123,
+			Kind:        "versioned_job",
+			Metadata:    []byte(`{"river:version":2}`),
+		}
+
+		// Steps leave args as they were, but the version bump is persisted.
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Equal(t, encodedArgs, job.EncodedArgs)
+		require.Len(t, exec.execCalls, 1)
+		require.Equal(t, []any{int64(123), string(encodedArgs), "river:version", "3"}, exec.execCalls[0].args)
+	})
+
+	t.Run("PersistArgsCurrentVersionNoOp", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job","description":"A description of a My Job.","version":3}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+		}
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Empty(t, exec.execCalls)
+	})
+
+	t.Run("PersistArgsTransformErrorNoOp", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"version":2}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+		}
+
+		require.Error(t, hook.WorkBegin(ctx, job))
+		require.Empty(t, exec.execCalls)
+	})
+
+	t.Run("PersistArgsErrorLogged", func(t *testing.T) {
+		t.Parallel()
+
+		exec := &testExecutor{err: errors.New("database unavailable")}
+		hook, _ := setupConfig(t, &versionedjob.HookConfig{
+			Driver:                &testDriver{databaseName: "postgres", exec: exec},
+			EnableArgsPersistence: true,
+			Transformers: []versionedjob.VersionTransformer{
+				newVersionedJobStepTransformer(),
+			},
+		})
+
+		job := &rivertype.JobRow{
+			EncodedArgs: []byte(`{"title":"My Job","version":2}`),
+			ID:          123,
+			Kind:        (VersionedJobArgs{}).Kind(),
+		}
+
+		// Job is still transformed and can be worked.
+		require.NoError(t, hook.WorkBegin(ctx, job))
+		require.Len(t, exec.execCalls, 1)
+		require.Equal(t, "A description of a My Job.", gjson.GetBytes(job.EncodedArgs, "description").String())
+	})
+
+	t.Run("PersistArgsPanicsWithoutDriver", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "driver is required with EnableArgsPersistence", func() {
+			versionedjob.NewHook(&versionedjob.HookConfig{
+				EnableArgsPersistence: true,
+			})
+		})
+	})
+
+	t.Run("PersistArgsPanicsWithUnsupportedDriver", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "EnableArgsPersistence is only supported for Postgres drivers", func() {
+			versionedjob.NewHook(&versionedjob.HookConfig{
+				Driver:                &testDriver{databaseName: "sqlite", exec: &testExecutor{}},
+				EnableArgsPersistence: true,
+			})
+		})
+	})
+}
+
+func TestHookPostgres(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	type testBundle struct {
+		exec   riverdriver.Executor
+		schema string
+	}
+
+	setupConfig := func(t *testing.T, config *versionedjob.HookConfig) (*versionedjob.Hook, *testBundle) {
+		t.Helper()
+
+		dbPool, err := pgxpool.New(ctx, riversharedtest.TestDatabaseURL())
+		require.NoError(t, err)
+		t.Cleanup(dbPool.Close)
+
+		var (
+			driver = riverpgxv5.New(dbPool)
+			schema = riverdbtest.TestSchema(ctx, t, driver, nil)
+		)
+
+		config.Driver = driver
+		config.EnableArgsPersistence = true
+		config.Schema = schema
+		if config.Transformers == nil {
+			config.Transformers = []versionedjob.VersionTransformer{newVersionedJobStepTransformer()}
+		}
+
+		return baseservice.Init(
+			riversharedtest.BaseServiceArchetype(t),
+			versionedjob.NewHook(config),
+		), &testBundle{
+			exec:   driver.GetExecutor(),
+			schema: schema,
+		}
+	}
+
+	getJob := func(t *testing.T, bundle *testBundle, id int64) *rivertype.JobRow {
+		t.Helper()
+
+		job, err := bundle.exec.JobGetByID(ctx, &riverdriver.JobGetByIDParams{ID: id, Schema: bundle.schema})
+		require.NoError(t, err)
+		return job
+	}
+
+	t.Run("PersistArgs", func(t *testing.T) {
+		t.Parallel()
+
+		hook, bundle := setupConfig(t, &versionedjob.HookConfig{})
+
+		job := testfactory.Job(ctx, t, bundle.exec, &testfactory.JobOpts{
+			EncodedArgs: []byte(`{"name":"My Job"}`),
+			Kind:        ptrutil.Ptr((VersionedJobArgs{}).Kind()),
+			Metadata:    []byte(`{"other":"value"}`),
+			Schema:      bundle.schema,
+		})
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+
+		persistedJob := getJob(t, bundle, job.ID)
+		require.Equal(t, VersionedJobArgs{
+			Title:       "My Job",
+			Description: "A description of a My Job.",
+			Version:     3,
+		}, mustUnmarshalJSON[VersionedJobArgs](t, persistedJob.EncodedArgs))
+		require.JSONEq(t, `{"other":"value"}`, string(persistedJob.Metadata))
+	})
+
+	t.Run("PersistArgsWithMetadataVersion", func(t *testing.T) {
+		t.Parallel()
+
+		hook, bundle := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+		})
+
+		job := testfactory.Job(ctx, t, bundle.exec, &testfactory.JobOpts{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			Kind:        ptrutil.Ptr((VersionedJobArgs{}).Kind()),
+			Metadata:    []byte(`{"other":"value","river:version":2}`),
+			Schema:      bundle.schema,
+		})
+
+		// Simulate metadata being changed by something else, like a worker
+		// recording output, after the job was fetched.
+		_, err := bundle.exec.JobUpdate(ctx, &riverdriver.JobUpdateParams{
+			ID:              job.ID,
+			MetadataDoMerge: true,
+			Metadata:        []byte(`{"output":"result"}`),
+			Schema:          bundle.schema,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+
+		persistedJob := getJob(t, bundle, job.ID)
+		require.JSONEq(t, `{"title":"My Job","description":"A description of a My Job."}`, string(persistedJob.EncodedArgs))
+		require.JSONEq(t, `{"other":"value","output":"result","river:version":3}`, string(persistedJob.Metadata))
+	})
+
+	t.Run("PersistMetadataVersionArgsUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		hook, bundle := setupConfig(t, &versionedjob.HookConfig{
+			EnableInsertVersionStamping: true,
+			MetadataVersionPath:         "river:version",
+			Transformers: []versionedjob.VersionTransformer{
+				newNoOpStepTransformer((VersionedJobArgs{}).Kind(), 3),
+			},
+		})
+
+		job := testfactory.Job(ctx, t, bundle.exec, &testfactory.JobOpts{
+			EncodedArgs: []byte(`{"title":"My Job"}`),
+			Kind:        ptrutil.Ptr((VersionedJobArgs{}).Kind()),
+			Metadata:    []byte(`{"river:version":1}`),
+			Schema:      bundle.schema,
+		})
+
+		require.NoError(t, hook.WorkBegin(ctx, job))
+
+		// Args are unchanged, but the version bump is persisted so steps
+		// aren't run again if the job is retried.
+		persistedJob := getJob(t, bundle, job.ID)
+		require.JSONEq(t, `{"title":"My Job"}`, string(persistedJob.EncodedArgs))
+		require.JSONEq(t, `{"river:version":3}`, string(persistedJob.Metadata))
+	})
+}
+
+type testDriver struct {
+	databaseName string
+	exec         *testExecutor
+}
+
+func (d *testDriver) DatabaseName() string { return d.databaseName }
+
+func (d *testDriver) GetExecutor() riverdriver.Executor { return d.exec }
+
+// testExecutor is an executor that records calls to Exec. Only the functions
+// used by Hook are implemented, and others will panic.
+type testExecutor struct {
+	riverdriver.Executor
+
+	err       error
+	execCalls []testExecCall
+}
+
+type testExecCall struct {
+	args []any
+	sql  string
+}
+
+func (e *testExecutor) Exec(ctx context.Context, sql string, args ...any) error {
+	e.execCalls = append(e.execCalls, testExecCall{args: args, sql: sql})
+	return e.err
+}
+
+// newVersionedJobStepTransformer returns a step transformer for
+// VersionedJobArgs equivalent to VersionedJobTransformer.
+func newVersionedJobStepTransformer() *versionedjob.StepTransformer {
+	return versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{
+		Kind: (VersionedJobArgs{}).Kind(),
+		Steps: []versionedjob.Step{
+			{
+				Transform: func(args []byte) ([]byte, error) {
+					args, err := sjson.SetBytes(args, "title", gjson.GetBytes(args, "name").String())
+					if err != nil {
+						return nil, err
+					}
+					return sjson.DeleteBytes(args, "name")
+				},
+				Version: 2,
+			},
+			{
+				Transform: func(args []byte) ([]byte, error) {
+					title := gjson.GetBytes(args, "title").String()
+					if title == "" {
+						return nil, errors.New("no title found in job args")
+					}
+					return sjson.SetBytes(args, "description", "A description of a "+title+".")
+				},
+				Version: 3,
+			},
+		},
+	})
+}
+
+// newNoOpStepTransformer returns a step transformer for the given kind whose
+// steps up to version don't change args.
+func newNoOpStepTransformer(kind string, version int) *versionedjob.StepTransformer {
+	steps := make([]versionedjob.Step, 0, version-1)
+	for v := 2; v <= version; v++ {
+		steps = append(steps, versionedjob.Step{
+			Transform: func(args []byte) ([]byte, error) { return args, nil },
+			Version:   v,
+		})
+	}
+
+	return versionedjob.NewStepTransformer(&versionedjob.StepTransformerConfig{Kind: kind, Steps: steps})
 }
 
 func mustMarshalJSON(t *testing.T, v any) []byte {